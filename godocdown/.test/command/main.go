@@ -0,0 +1,41 @@
+/*
+Command command does nothing, quickly.
+
+	$ command -fast input.txt
+
+Options
+
+It also reads
+.config files.
+*/
+package main
+
+import (
+	"flag"
+)
+
+const defaultCount = 3
+
+var (
+	fast  = flag.Bool("fast", false, "Do nothing faster")
+	count int
+	_     = flag.String("hidden", "", "\x00")
+)
+
+// Not a flag, but looks like one
+type config struct{}
+
+func (config) String(key, fallback, usage string) string {
+	return fallback
+}
+
+var cfg config
+
+var _ = cfg.String("key", "fallback", "Not a flag")
+
+func main() {
+	flag.IntVar(&count, "count", defaultCount, "How many times to do nothing")
+	set := flag.NewFlagSet("command", flag.ExitOnError)
+	set.Int("depth", 1, "How deep to do nothing")
+	flag.Parse()
+}
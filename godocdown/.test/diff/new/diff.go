@@ -0,0 +1,36 @@
+// Package diff is the new version of a package.
+package diff
+
+// The size of things
+const Size = 2
+
+// The kinds of record
+const (
+	First = iota
+	hidden
+	Between
+	Second
+	Third
+)
+
+var Default = Record{Name: "default"}
+
+// Record is a record of something.
+type Record struct {
+	Name  string
+	Count int64
+	Tag   string `json:"tag"`
+}
+
+// Open opens a record.
+func Open(name string, create bool) (*Record, error) {
+	return nil, nil
+}
+
+// Close closes the record.
+func (self *Record) Close() {}
+
+// Flush flushes the record.
+func (self *Record) Flush() error {
+	return nil
+}
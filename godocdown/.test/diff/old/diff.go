@@ -0,0 +1,32 @@
+// Package diff is the old version of a package.
+package diff
+
+// The size of things
+const Size = 1
+
+// The kinds of record
+const (
+	First = iota
+	hidden
+	Second
+	Third
+)
+
+var Default = Record{}
+
+// Record is a record of something.
+type Record struct {
+	Name  string
+	Count int
+}
+
+// Remove is going away.
+func Remove() {}
+
+// Open opens a record.
+func Open(name string) *Record {
+	return nil
+}
+
+// Close closes the record.
+func (self *Record) Close() {}
@@ -0,0 +1,21 @@
+// Package examples has examples in its tests
+package examples
+
+import (
+	"fmt"
+)
+
+// Hello says hello
+func Hello(name string) {
+	fmt.Printf("Hello, %s\n", name)
+}
+
+// Greeter greets
+type Greeter struct {
+	Name string
+}
+
+// Greet greets with Hello
+func (self Greeter) Greet() {
+	Hello(self.Name)
+}
@@ -0,0 +1,22 @@
+package examples_test
+
+import (
+	"../examples"
+)
+
+func Example() {
+	examples.Hello("World")
+	// Output: Hello, World
+}
+
+// With a different name
+func ExampleHello_alice() {
+	// Say hello
+	examples.Hello("Alice")
+	// Output:
+	// Hello, Alice
+}
+
+func ExampleGreeter_Greet() {
+	examples.Greeter{Name: "Bob"}.Greet()
+}
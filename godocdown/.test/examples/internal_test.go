@@ -0,0 +1,13 @@
+package examples
+
+import (
+	"testing"
+)
+
+// TestHelper is a helper of the tests
+func TestHelper(t *testing.T) {
+}
+
+func ExampleGreeter() {
+	_ = Greeter{Name: "Carol"}
+}
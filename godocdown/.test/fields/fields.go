@@ -0,0 +1,21 @@
+// Package fields has a struct with documented fields
+package fields
+
+// Base is embedded
+type Base struct {
+	ID int `json:"id"`
+}
+
+// Record is a record
+type Record struct {
+	Base
+
+	// Name is the *name* of the record
+	Name string `json:"name,omitempty" xml:"name"`
+
+	Size, Count int // The size | count
+
+	Parent *Record
+
+	hidden bool
+}
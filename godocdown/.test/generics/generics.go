@@ -0,0 +1,75 @@
+// Package generics has generic functions and types
+package generics
+
+import (
+	"cmp"
+)
+
+// Number is a constraint for numeric types
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// Keyed is used as a constraint
+type Keyed interface {
+	comparable
+	Key() string
+}
+
+// Lister is an ordinary interface
+type Lister interface {
+	List() []string
+}
+
+// Ordered is an ordinary interface, named like cmp.Ordered
+type Ordered interface {
+	Less(other Ordered) bool
+}
+
+// List is a generic list
+type List[T any] struct {
+	items []T
+}
+
+// Pair is a generic pair
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// NewList creates a List
+func NewList[T any](items ...T) *List[T] {
+	return &List[T]{items: items}
+}
+
+// MakePair creates a Pair
+func MakePair[K comparable, V any](key K, value V) Pair[K, V] {
+	return Pair[K, V]{key, value}
+}
+
+// Push adds an item
+func (list *List[T]) Push(item T) {
+	list.items = append(list.items, item)
+}
+
+// Sum adds numbers
+func Sum[N Number](values ...N) N {
+	var sum N
+	for _, value := range values {
+		sum += value
+	}
+	return sum
+}
+
+// Index finds a keyed value
+func Index[K Keyed](values []K) map[string]K {
+	return nil
+}
+
+// Max returns the larger value
+func Max[T cmp.Ordered](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
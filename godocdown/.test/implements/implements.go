@@ -0,0 +1,47 @@
+// Package implements has types that implement interfaces
+package implements
+
+import (
+	"fmt"
+)
+
+// Lister lists things
+type Lister interface {
+	List() []string
+}
+
+// Names is a list of names
+type Names []string
+
+// List returns the names
+func (names Names) List() []string {
+	return names
+}
+
+// String returns the names, joined
+func (names Names) String() string {
+	return fmt.Sprint([]string(names))
+}
+
+// Buffer is a buffer
+type Buffer struct {
+	data []byte
+}
+
+// Write appends to the buffer
+func (buffer *Buffer) Write(data []byte) (int, error) {
+	buffer.data = append(buffer.data, data...)
+	return len(data), nil
+}
+
+// List returns the buffer as a list
+func (buffer *Buffer) List() []string {
+	return []string{string(buffer.data)}
+}
+
+// Failure is an error
+type Failure struct{}
+
+func (Failure) Error() string {
+	return "failure"
+}
@@ -0,0 +1,16 @@
+//godocdown:markdown
+
+// Package markdown has documentation written in **Markdown**
+//
+// | Name | Value |
+// |------|-------|
+// | a_b  | 1     |
+//
+// Install it with go get.
+//
+// - first
+// - second
+package markdown
+
+// Value is a *value*, see [the docs](http://example.com)
+const Value = 1
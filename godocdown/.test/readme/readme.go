@@ -0,0 +1,8 @@
+// Package readme has a type named like the index of -split
+package readme
+
+// README is a type, not the index
+type README struct{}
+
+// Other is another type
+type Other int
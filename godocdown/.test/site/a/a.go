@@ -0,0 +1,7 @@
+// Package a is a
+package a
+
+import "example.com/site/c"
+
+// A is [c.C], which is c.C
+var A = c.C
@@ -0,0 +1,2 @@
+// Package b is below a
+package b
@@ -0,0 +1,5 @@
+// Package c is c
+package c
+
+// C is c
+const C = 1
@@ -0,0 +1,7 @@
+// Package a is written in AsciiDoc
+package a
+
+import "example.com/siteformat/b"
+
+// A is b.B
+var A = b.B
@@ -0,0 +1,5 @@
+// Package b is b
+package b
+
+// B is b
+const B = 1
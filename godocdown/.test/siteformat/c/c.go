@@ -0,0 +1,7 @@
+// Package c is c
+package c
+
+import "example.com/siteformat/b"
+
+// C is b.B
+var C = b.B
@@ -0,0 +1,3 @@
+module example.com/siteformat
+
+go 1.21
@@ -0,0 +1,17 @@
+// Package unresolved imports a package that can't be found
+package unresolved
+
+import (
+	"example.com/missing"
+)
+
+// Name is a name
+type Name string
+
+// String returns the name
+func (name Name) String() string {
+	return string(name)
+}
+
+// Missing is of the missing package
+var Missing missing.Thing
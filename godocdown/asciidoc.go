@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A listing block, as emitted by _asciidocRenderer.CodeBlock
+var listing_Regexp = regexp.MustCompile("(?ms)^----\n.*?^----[ \t]*$")
+
+// _asciidocRenderer renders AsciiDoc (for Asciidoctor)
+type _asciidocRenderer struct{}
+
+func (_asciidocRenderer) Extension() string {
+	return ".adoc"
+}
+
+func (self _asciidocRenderer) Header(title, importPath string) string {
+	resetHeadings()
+	header := formatHeading(RenderStyle.TitleHeader, title) + "\n"
+	if importPath != "" {
+		header += fmt.Sprintf("\n%s\n\n", self.CodeBlock("go", fmt.Sprintf("import \"%s\"", importPath)))
+	}
+	return header
+}
+
+func (_asciidocRenderer) Synopsis(text string) string {
+	return headifySynopsis(text)
+}
+
+// Heading returns a section title, the levels of which must not skip
+// (see contiguousLevel)
+func (_asciidocRenderer) Heading(level int, text string) string {
+	level = contiguousLevel(level)
+	return fmt.Sprintf("%s %s", strings.Repeat("=", level), text)
+}
+
+func (_asciidocRenderer) CodeBlock(language, source string) string {
+	if language == "" {
+		return fmt.Sprintf("----\n%s\n----", source)
+	}
+	return fmt.Sprintf("[source,%s]\n----\n%s\n----", language, source)
+}
+
+func (_asciidocRenderer) CodeBlockRegexp() *regexp.Regexp {
+	return listing_Regexp
+}
+
+func (_asciidocRenderer) Paragraph(text string) string {
+	return text + "\n"
+}
+
+func (_asciidocRenderer) Escape(word string, lineStart bool, spans *_codeSpans) string {
+	return escapeAsciiDoc(word, lineStart, spans)
+}
+
+func (_asciidocRenderer) CodeSpan(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`+" + text + "+`"
+}
+
+func (_asciidocRenderer) Emphasis(text string) string {
+	return "_" + text + "_"
+}
+
+func (_asciidocRenderer) Link(text, target string) string {
+	if strings.HasSuffix(target, ".adoc") && !strings.Contains(target, "://") {
+		// A cross reference, so that it points to the converted page
+		return fmt.Sprintf("xref:%s[%s]", target, text)
+	}
+	return fmt.Sprintf("link:%s[%s]", target, text)
+}
+
+func (_asciidocRenderer) Badges(badgeList []_badge) string {
+	textList := make([]string, 0, len(badgeList))
+	for _, badge := range badgeList {
+		textList = append(textList, fmt.Sprintf("image:%s[\"%s\",link=\"%s\"]", badge.image, badge.alt, badge.link))
+	}
+	return strings.Join(textList, " ") + "\n"
+}
+
+func (_asciidocRenderer) List(itemList []string) string {
+	var buffer bytes.Buffer
+	for _, item := range itemList {
+		fmt.Fprintf(&buffer, "* %s\n", item)
+	}
+	return buffer.String()
+}
+
+func (_asciidocRenderer) Table(header []string, rowList [][]string) string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "[options=\"header\"]\n|===\n")
+	for _, cell := range header {
+		fmt.Fprintf(&buffer, "|%s ", cell)
+	}
+	fmt.Fprintf(&buffer, "\n")
+	for _, row := range rowList {
+		fmt.Fprintf(&buffer, "\n")
+		for _, cell := range row {
+			fmt.Fprintf(&buffer, "|%s\n", cell)
+		}
+	}
+	fmt.Fprintf(&buffer, "|===\n")
+	return buffer.String()
+}
+
+func (_asciidocRenderer) TableCell(cell string) string {
+	return strings.Replace(cell, "|", `\|`, -1)
+}
+
+func (_asciidocRenderer) Signature() string {
+	return "\n\n'''\n*godocdown* http://github.com/robertkrimen/godocdown\n"
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"text/template/parse"
+)
+
+// A badge (an image, linking somewhere) of the header (see -badges)
+type _badge struct {
+	alt   string
+	image string
+	link  string
+}
+
+var licenseFilenameList = strings.Fields(`
+	LICENSE
+	LICENSE.md
+	LICENSE.txt
+	LICENCE
+	COPYING
+`)
+
+// The licenses detectLicense knows about, by identifying phrases
+// (every one of which the text must contain), most specific first
+var licenseList = []struct {
+	name       string
+	phraseList []string
+}{
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"BSD-3-Clause", []string{"Redistribution and use", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute"}},
+	{"Unlicense", []string{"This is free and unencumbered software"}},
+}
+
+// detectLicense returns the name (an SPDX identifier) of the license text, or "" if unknown
+func detectLicense(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, license := range licenseList {
+		match := true
+		for _, phrase := range license.phraseList {
+			match = match && strings.Contains(text, phrase)
+		}
+		if match {
+			return license.name
+		}
+	}
+	return ""
+}
+
+// shieldsBadge returns the URL of a static badge from shields.io
+func shieldsBadge(label, message, color string) string {
+	escape := func(text string) string {
+		text = strings.NewReplacer("-", "--", "_", "__").Replace(text)
+		return url.PathEscape(text)
+	}
+	return fmt.Sprintf("https://img.shields.io/badge/%s-%s-%s.svg", escape(label), escape(message), color)
+}
+
+// expandBadgePattern replaces {import}, {module}, and {repository} (the
+// module path without its host, e.g. "user/project") in a URL pattern
+func expandBadgePattern(pattern, importPath, modulePath string) string {
+	repository := modulePath
+	if index := strings.Index(repository, "/"); index >= 0 {
+		repository = repository[index+1:]
+	}
+	return strings.NewReplacer(
+		"{import}", importPath,
+		"{module}", modulePath,
+		"{repository}", repository,
+	).Replace(pattern)
+}
+
+// findBadges returns the badges of the package of document: pkg.go.dev,
+// Go Report Card, the license (from a LICENSE file of the module, or of
+// the package), the minimum version of Go (from go.mod), and CI (from
+// RenderStyle.CIBadge, if any).
+func findBadges(document *_document) []_badge {
+	module := findModule(document)
+	modulePath := ""
+	if module != nil {
+		modulePath = module.path
+	}
+	importPath := resolveImportPath(document, module)
+
+	var badgeList []_badge
+	if importPath != "" {
+		badgeList = append(badgeList, _badge{
+			alt:   "Go Reference",
+			image: "https://pkg.go.dev/badge/" + importPath + ".svg",
+			link:  "https://pkg.go.dev/" + importPath,
+		})
+	}
+	if modulePath != "" {
+		badgeList = append(badgeList, _badge{
+			alt:   "Go Report Card",
+			image: "https://goreportcard.com/badge/" + modulePath,
+			link:  "https://goreportcard.com/report/" + modulePath,
+		})
+	}
+
+	licenseFS, licenseDirectory, licenseRelative := document.fsys, document.directory, "."
+	if module != nil {
+		licenseFS, licenseDirectory, licenseRelative = module.fsys, module.directory, module.relative
+	}
+	if licenseFS != nil {
+		for _, filename := range licenseFilenameList {
+			content, err := readFS(licenseFS, licenseDirectory, filename)
+			if err != nil {
+				continue
+			}
+			license := detectLicense(string(content))
+			if license == "" {
+				license = "see " + filename
+			}
+			badgeList = append(badgeList, _badge{
+				alt:   "License: " + license,
+				image: shieldsBadge("license", license, "blue"),
+				link:  document.fileLink(path.Join(licenseRelative, filename)),
+			})
+			break
+		}
+	}
+
+	if module != nil && module.goVersion != "" {
+		badgeList = append(badgeList, _badge{
+			alt:   "Go " + module.goVersion,
+			image: shieldsBadge("go", ">="+module.goVersion, "00ADD8"),
+			link:  "https://go.dev/dl/",
+		})
+	}
+
+	if RenderStyle.CIBadge != "" {
+		image := expandBadgePattern(RenderStyle.CIBadge, importPath, modulePath)
+		link := image
+		if RenderStyle.CILink != "" {
+			link = expandBadgePattern(RenderStyle.CILink, importPath, modulePath)
+		}
+		badgeList = append(badgeList, _badge{
+			alt:   "CI",
+			image: image,
+			link:  link,
+		})
+	}
+	return badgeList
+}
+
+// placesBadges reports whether a template (the tree of it, from node) calls
+// EmitBadges (or EmitBadgesTo), placing the badges itself
+func placesBadges(node parse.Node) bool {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return false
+		}
+		for _, child := range node.Nodes {
+			if placesBadges(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return placesBadges(node.Pipe)
+	case *parse.PipeNode:
+		if node == nil {
+			return false
+		}
+		for _, command := range node.Cmds {
+			if placesBadges(command) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, argument := range node.Args {
+			if placesBadges(argument) {
+				return true
+			}
+		}
+	case *parse.FieldNode:
+		for _, identifier := range node.Ident {
+			if identifier == "EmitBadges" || identifier == "EmitBadgesTo" {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return placesBadges(node.Pipe) || placesBadges(node.List) || placesBadges(node.ElseList)
+	case *parse.RangeNode:
+		return placesBadges(node.Pipe) || placesBadges(node.List) || placesBadges(node.ElseList)
+	case *parse.WithNode:
+		return placesBadges(node.Pipe) || placesBadges(node.List) || placesBadges(node.ElseList)
+	}
+	return false
+}
+
+func renderBadgesTo(writer io.Writer, document *_document) {
+	if badgeList := findBadges(document); len(badgeList) > 0 {
+		fmt.Fprintf(writer, "%s\n", renderer().Badges(badgeList))
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	Flag "flag"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A cache of what was generated from each package, so that a package whose
+// inputs (source files, template, and options) are unchanged can be skipped.
+// There is one for each output directory (of -output, -split, or -site),
+// kept under -cache (see cacheFilename).
+type _cache struct {
+	filename  string
+	Directory string                  // The output directory (absolute)
+	Entries   map[string]*_cacheEntry // By the absolute directory of the package
+}
+
+// An entry of the cache: the hash of the inputs of a package, the hash of
+// each file written from them, and (for -site) the documentation itself
+type _cacheEntry struct {
+	cache   *_cache
+	Input   string
+	Output  map[string]string `json:",omitempty"` // Relative to the output directory
+	Package *_cachePackage    `json:",omitempty"`
+
+	previous map[string]string // The Output of the entry this one replaces (see removeStale)
+}
+
+// A package of a site, as cached (see _sitePackage)
+type _cachePackage struct {
+	ImportPath string
+	Name       string
+	Synopsis   string
+	Imports    []string `json:",omitempty"`
+	Body       string
+}
+
+// cacheFilename returns the file of the cache of the output directory, named
+// after its absolute path, in -cache (by default, godocdown in the user cache
+// directory), or "" if there is nowhere to keep it.
+func cacheFilename(directory string) string {
+	root := *flag_cache
+	if root == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		root = filepath.Join(userCache, "godocdown")
+	}
+	return filepath.Join(root, kilt.Sha1([]byte(directory))+".json")
+}
+
+// loadCache reads the cache of the output directory. A missing (or
+// unreadable) cache is empty, and without a place for it there is none
+// (nil). With -force, no entry of it is fresh (see lookup).
+func loadCache(directory string) *_cache {
+	absolute, err := filepath.Abs(directory)
+	if err != nil {
+		return nil
+	}
+	filename := cacheFilename(absolute)
+	if filename == "" {
+		return nil
+	}
+	cache := &_cache{
+		filename:  filename,
+		Directory: absolute,
+		Entries:   map[string]*_cacheEntry{},
+	}
+	content, err := ioutil.ReadFile(cache.filename)
+	if err != nil {
+		return cache
+	}
+	if json.Unmarshal(content, cache) != nil || cache.Entries == nil {
+		cache.Entries = map[string]*_cacheEntry{}
+	}
+	cache.Directory = absolute
+	return cache
+}
+
+func (self *_cache) save() error {
+	if self == nil {
+		return nil
+	}
+	content, err := json.MarshalIndent(self, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFile(self.filename, append(content, '\n'), true)
+}
+
+// lookup returns the entry for the package in directory, and whether it is
+// fresh: its inputs are unchanged, and the files written from them are as
+// they were written. An entry that isn't fresh is started anew.
+func (self *_cache) lookup(directory string, extra ...string) (*_cacheEntry, bool) {
+	if self == nil {
+		return nil, false
+	}
+	key, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, false
+	}
+	input := inputHash(directory, extra...)
+	previous := self.Entries[key]
+	if previous != nil && previous.Input == input && !*flag_force {
+		previous.cache = self
+		if previous.intact() {
+			return previous, true
+		}
+	}
+	entry := &_cacheEntry{
+		cache:  self,
+		Input:  input,
+		Output: map[string]string{},
+	}
+	if previous != nil {
+		entry.previous = previous.Output
+	}
+	self.Entries[key] = entry
+	return entry, false
+}
+
+// write writes content to filename, unless the file already has that
+// content (with -force, always), and reports the file as regenerated.
+// Without a cache, it is just writeFile.
+func (self *_cache) write(filename string, content []byte, createParent bool) error {
+	if self == nil {
+		return writeFile(filename, content, createParent)
+	}
+	if !*flag_force && kilt.Sha1Path(filename) == kilt.Sha1(content) {
+		return nil
+	}
+	err := writeFile(filename, content, createParent)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", filename)
+	return nil
+}
+
+func (self *_cacheEntry) intact() bool {
+	for filename, hash := range self.Output {
+		if kilt.Sha1Path(self.cache.Directory, filename) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// removeStale removes the files generated from the package before that
+// weren't this time, unless they were changed since.
+func (self *_cacheEntry) removeStale() error {
+	if self == nil {
+		return nil
+	}
+	directory := self.cache.Directory
+	for filename, hash := range self.previous {
+		if _, exists := self.Output[filename]; exists || kilt.Sha1Path(directory, filename) != hash {
+			continue
+		}
+		err := os.Remove(filepath.Join(directory, filename))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "removed %s\n", filepath.Join(directory, filename))
+	}
+	return nil
+}
+
+// write writes (see _cache.write) a file generated from the package of the entry
+func (self *_cacheEntry) write(filename string, content []byte, createParent bool) error {
+	if self == nil {
+		return writeFile(filename, content, createParent)
+	}
+	err := self.cache.write(filename, content, createParent)
+	if err != nil {
+		return err
+	}
+	absolute, err := filepath.Abs(filename)
+	if err != nil {
+		return nil
+	}
+	if relative, err := filepath.Rel(self.cache.Directory, absolute); err == nil {
+		self.Output[relative] = kilt.Sha1(content)
+	}
+	return nil
+}
+
+var _executableHash *string
+
+// executableHash is the hash of godocdown itself, as a different
+// version of godocdown may generate different documentation
+func executableHash() string {
+	if _executableHash == nil {
+		hash := ""
+		if path, err := os.Executable(); err == nil {
+			hash = kilt.Sha1Path(path)
+		}
+		_executableHash = &hash
+	}
+	return *_executableHash
+}
+
+// inputHash hashes everything the documentation of the package in directory
+// is generated from: the source files (and tests, for their examples),
+// .godocdown.import, the template, the options, and anything extra. With
+// badges, installation, or dependencies, that includes the go.mod and LICENSE
+// of the module (see moduleInput).
+func inputHash(directory string, extra ...string) string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "%s\n%s", executableHash(), styleInput(RenderStyle))
+	flag.VisitAll(func(entry *Flag.Flag) {
+		if entry.Name != "force" && entry.Name != "cache" {
+			fmt.Fprintf(&buffer, "-%s=%s\n", entry.Name, entry.Value)
+		}
+	})
+
+	absolute, _ := filepath.Abs(directory)
+	fmt.Fprintf(&buffer, "%s\n", absolute)
+	fileList, _ := ioutil.ReadDir(directory)
+	for _, file := range fileList {
+		name := file.Name()
+		isTest := strings.HasSuffix(name, "_test.go")
+		if name == ".godocdown.import" || (name[0] != '.' && strings.HasSuffix(name, ".go") && (!isTest || RenderStyle.IncludeExamples)) {
+			fmt.Fprintf(&buffer, "%s %s\n", name, kilt.Sha1Path(directory, name))
+		}
+	}
+
+	if templatePath := templatePathOf(directory); templatePath != "" {
+		fmt.Fprintf(&buffer, "template %s\n", kilt.Sha1Path(templatePath))
+	}
+	if RenderStyle.IncludeBadges || RenderStyle.IncludeInstall || RenderStyle.IncludeDependencies {
+		buffer.WriteString(moduleInput(directory))
+	}
+	for _, value := range extra {
+		fmt.Fprintf(&buffer, "%s\n", value)
+	}
+	return kilt.Sha1(buffer.Bytes())
+}
+
+// styleInput lists the fields of style that documentation is rendered with,
+// one per line, by name. (SiteLinks is left out: for -site, the pages it is
+// made from are hashed instead.)
+func styleInput(style Style) string {
+	synopsisHeading := ""
+	if style.SynopsisHeading != nil {
+		synopsisHeading = style.SynopsisHeading.String()
+	}
+	var buffer bytes.Buffer
+	for _, field := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"IncludeImport", style.IncludeImport},
+		{"Format", style.Format},
+		{"TitleHeader", style.TitleHeader},
+		{"SynopsisHeader", style.SynopsisHeader},
+		{"SynopsisHeading", synopsisHeading},
+		{"UsageHeader", style.UsageHeader},
+		{"ConstantHeader", style.ConstantHeader},
+		{"VariableHeader", style.VariableHeader},
+		{"FunctionHeader", style.FunctionHeader},
+		{"TypeHeader", style.TypeHeader},
+		{"TypeFunctionHeader", style.TypeFunctionHeader},
+		{"ConstraintHeader", style.ConstraintHeader},
+		{"HeadingOffset", style.HeadingOffset},
+		{"HeadingSetext", style.HeadingSetext},
+		{"Width", style.Width},
+		{"NoWrap", style.NoWrap},
+		{"KeepLines", style.KeepLines},
+		{"EscapeMarkdown", style.EscapeMarkdown},
+		{"MarkdownComments", style.MarkdownComments},
+		{"IncludeFields", style.IncludeFields},
+		{"IncludeImplements", style.IncludeImplements},
+		{"IncludeExamples", style.IncludeExamples},
+		{"IncludeBadges", style.IncludeBadges},
+		{"CIBadge", style.CIBadge},
+		{"CILink", style.CILink},
+		{"IncludeInstall", style.IncludeInstall},
+		{"IncludeDependencies", style.IncludeDependencies},
+		{"SiteFormat", style.SiteFormat},
+		{"IncludeSignature", style.IncludeSignature},
+	} {
+		fmt.Fprintf(&buffer, "%s=%v\n", field.name, field.value)
+	}
+	return buffer.String()
+}
+
+// moduleInput lists (with their hashes) the files of the module of the package
+// in directory that its documentation can be generated from: the go.mod, and
+// the LICENSE of the module (or of the package, without one).
+func moduleInput(directory string) string {
+	var buffer bytes.Buffer
+	licenseDirectory := directory
+	if module := findModule(&_document{buildPkg: &build.Package{Dir: directory}}); module != nil {
+		// Read from disk, so module.directory is "."
+		licenseDirectory = filepath.Join(directory, filepath.FromSlash(module.relative))
+		fmt.Fprintf(&buffer, "go.mod %s\n", kilt.Sha1Path(licenseDirectory, "go.mod"))
+	}
+	for _, filename := range licenseFilenameList {
+		if hash := kilt.Sha1Path(licenseDirectory, filename); hash != "" {
+			fmt.Fprintf(&buffer, "%s %s\n", filename, hash)
+		}
+	}
+	return buffer.String()
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// An exported symbol of a package (see apiOf)
+type _apiSymbol struct {
+	kind        string
+	name        string
+	declaration string
+}
+
+// The kinds of symbol, in the order they are reported
+var apiKindList = []string{"constant", "variable", "function", "type", "method", "field"}
+
+func apiKindIndex(kind string) int {
+	for index, value := range apiKindList {
+		if kind == value {
+			return index
+		}
+	}
+	return len(apiKindList)
+}
+
+func (self _apiSymbol) key() string {
+	return self.kind + " " + self.name
+}
+
+// apiOf returns the exported symbols of the document, by key. This has to be
+// done before the next loadDocument, since sourceOfNode uses the (global) fset.
+func apiOf(document *_document) map[string]_apiSymbol {
+	api := map[string]_apiSymbol{}
+	add := func(kind, name, declaration string) {
+		symbol := _apiSymbol{kind: kind, name: name, declaration: declaration}
+		api[symbol.key()] = symbol
+	}
+	values := func(list []*doc.Value) {
+		for _, entry := range list {
+			for _, spec := range entry.Decl.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				kind := "variable"
+				if entry.Decl.Tok.String() == "const" {
+					kind = "constant"
+				}
+				for _, name := range valueSpec.Names {
+					if !name.IsExported() {
+						continue
+					}
+					if kind == "constant" {
+						declaration, exists := document.constants[name.Name]
+						if !exists {
+							declaration = "const " + sourceOfNode(valueSpec)
+						}
+						add(kind, name.Name, declaration)
+					} else {
+						// The value of a variable isn't part of the API, only its type
+						add(kind, name.Name, strings.TrimSpace("var "+name.Name+" "+variableType(valueSpec)))
+					}
+				}
+			}
+		}
+	}
+	functions := func(list []*doc.Func) {
+		for _, entry := range list {
+			if entry.Recv != "" {
+				receiver := strings.TrimPrefix(entry.Recv, "*")
+				if index := strings.Index(receiver, "["); index >= 0 {
+					receiver = receiver[:index]
+				}
+				add("method", receiver+"."+entry.Name, sourceOfNode(entry.Decl))
+				continue
+			}
+			add("function", entry.Name, sourceOfNode(entry.Decl))
+		}
+	}
+
+	values(document.pkg.Consts)
+	values(document.pkg.Vars)
+	functions(document.pkg.Funcs)
+	for _, entry := range document.pkg.Types {
+		spec := typeSpecOf(entry)
+		if spec == nil {
+			continue
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			// The fields are compared on their own
+			add("type", entry.Name, "type "+entry.Name+typeTypeParams(entry)+" struct")
+			for _, field := range fieldsOf(entry) {
+				add("field", entry.Name+"."+field.name, strings.TrimSpace(field.kind+" "+field.tag))
+			}
+		} else {
+			add("type", entry.Name, "type "+sourceOfNode(spec))
+		}
+		values(entry.Consts)
+		values(entry.Vars)
+		functions(entry.Funcs)
+		functions(entry.Methods)
+	}
+	return api
+}
+
+// findConstants returns the declaration of each constant of the package
+// (which must not have been trimmed by doc.New yet, as that would change
+// the value of iota), by name. A constant without a value has the type and
+// value it repeats, and a value with iota has the value of iota, e.g.:
+//
+//	const Second = iota // iota = 1
+func findConstants(pkg *ast.Package) map[string]string {
+	constants := map[string]string{}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+			var repeated *ast.ValueSpec
+			for index, spec := range genDecl.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				declaration := "const " + sourceOfNode(valueSpec)
+				if len(valueSpec.Values) > 0 {
+					repeated = valueSpec
+				} else if repeated != nil {
+					nameList := make([]string, 0, len(valueSpec.Names))
+					for _, name := range valueSpec.Names {
+						nameList = append(nameList, name.Name)
+					}
+					valueList := make([]string, 0, len(repeated.Values))
+					for _, value := range repeated.Values {
+						valueList = append(valueList, sourceOfNode(value))
+					}
+					declaration = "const " + strings.Join(nameList, ", ")
+					if repeated.Type != nil {
+						declaration += " " + sourceOfNode(repeated.Type)
+					}
+					declaration += " = " + strings.Join(valueList, ", ")
+				}
+				if repeated != nil && usesIota(repeated.Values) {
+					declaration += fmt.Sprintf(" // iota = %d", index)
+				}
+				for _, name := range valueSpec.Names {
+					constants[name.Name] = declaration
+				}
+			}
+		}
+	}
+	return constants
+}
+
+func usesIota(valueList []ast.Expr) bool {
+	uses := false
+	for _, value := range valueList {
+		ast.Inspect(value, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok && ident.Name == "iota" {
+				uses = true
+			}
+			return !uses
+		})
+	}
+	return uses
+}
+
+// variableType returns the declared type of a variable, or the type of
+// its value, if that's a composite literal (e.g. Style{...}).
+func variableType(spec *ast.ValueSpec) string {
+	if spec.Type != nil {
+		return sourceOfNode(spec.Type)
+	}
+	if len(spec.Values) == 1 {
+		value := spec.Values[0]
+		if unary, ok := value.(*ast.UnaryExpr); ok {
+			if literal, ok := unary.X.(*ast.CompositeLit); ok && literal.Type != nil {
+				return "*" + sourceOfNode(literal.Type)
+			}
+		}
+		if literal, ok := value.(*ast.CompositeLit); ok && literal.Type != nil {
+			return sourceOfNode(literal.Type)
+		}
+	}
+	return ""
+}
+
+type _apiSymbolSort []_apiSymbol
+
+func (self _apiSymbolSort) Len() int           { return len(self) }
+func (self _apiSymbolSort) Swap(i, j int)      { self[i], self[j] = self[j], self[i] }
+func (self _apiSymbolSort) Less(i, j int) bool { return apiSymbolLess(self[i], self[j]) }
+
+func apiSymbolLess(a, b _apiSymbol) bool {
+	if a.kind != b.kind {
+		return apiKindIndex(a.kind) < apiKindIndex(b.kind)
+	}
+	return a.name < b.name
+}
+
+// diffAPI compares the symbols of two versions of a package.
+func diffAPI(oldAPI, newAPI map[string]_apiSymbol) (addedList, removedList []_apiSymbol, changedList [][2]_apiSymbol) {
+	for key, symbol := range newAPI {
+		previous, exists := oldAPI[key]
+		if !exists {
+			addedList = append(addedList, symbol)
+		} else if previous.declaration != symbol.declaration {
+			changedList = append(changedList, [2]_apiSymbol{previous, symbol})
+		}
+	}
+	for key, symbol := range oldAPI {
+		if _, exists := newAPI[key]; !exists {
+			removedList = append(removedList, symbol)
+		}
+	}
+	sort.Sort(_apiSymbolSort(addedList))
+	sort.Sort(_apiSymbolSort(removedList))
+	sort.Slice(changedList, func(i, j int) bool {
+		return apiSymbolLess(changedList[i][1], changedList[j][1])
+	})
+	return
+}
+
+// renderDiffTo renders the changes to the API of a package, from one version to another.
+func renderDiffTo(writer io.Writer, name, from, to string, oldAPI, newAPI map[string]_apiSymbol) {
+	render := renderer()
+	addedList, removedList, changedList := diffAPI(oldAPI, newAPI)
+
+	fmt.Fprintf(writer, "%s\n\n", formatHeading(RenderStyle.UsageHeader, "API Changes"))
+	fmt.Fprintf(writer, "%s\n", render.Paragraph(fmt.Sprintf("Changes to %s from %s to %s.", render.CodeSpan(name), render.CodeSpan(from), render.CodeSpan(to))))
+
+	if len(addedList) == 0 && len(removedList) == 0 && len(changedList) == 0 {
+		fmt.Fprintf(writer, "%s\n", render.Paragraph("No changes."))
+		return
+	}
+
+	list := func(heading string, symbolList []_apiSymbol) {
+		if len(symbolList) == 0 {
+			return
+		}
+		itemList := make([]string, 0, len(symbolList))
+		for _, symbol := range symbolList {
+			itemList = append(itemList, symbol.kind+" "+render.CodeSpan(symbol.name))
+		}
+		fmt.Fprintf(writer, "%s\n\n%s\n", formatHeading(RenderStyle.UsageHeader+1, heading), render.List(itemList))
+	}
+	list("Added", addedList)
+	list("Removed", removedList)
+
+	if len(changedList) > 0 {
+		fmt.Fprintf(writer, "%s\n\n", formatHeading(RenderStyle.UsageHeader+1, "Changed"))
+		for _, change := range changedList {
+			var lineList []string
+			for _, line := range strings.Split(change[0].declaration, "\n") {
+				lineList = append(lineList, "- "+line)
+			}
+			for _, line := range strings.Split(change[1].declaration, "\n") {
+				lineList = append(lineList, "+ "+line)
+			}
+			heading := change[1].kind + " " + render.CodeSpan(change[1].name)
+			fmt.Fprintf(writer, "%s\n\n%s\n\n", formatHeading(RenderStyle.UsageHeader+2, heading), render.CodeBlock("diff", strings.Join(lineList, "\n")))
+		}
+	}
+}
+
+// loadAPI loads the symbols of one side of a diff: either a directory, or
+// a git revision of the package target.
+func loadAPI(side, target string) (string, map[string]_apiSymbol, error) {
+	var document *_document
+	var err error
+	if info, statErr := os.Stat(side); statErr == nil && info.IsDir() {
+		document, err = loadDocument(side)
+	} else {
+		document, err = loadRevision(target, side)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if document == nil {
+		return "", nil, fmt.Errorf("Could not find package: %s", side)
+	}
+	return document.Name, apiOf(document), nil
+}
+
+// emitDiff renders the changes to the API of a package between two git
+// revisions, or two directories (see -diff OLD,NEW).
+func emitDiff(from, to, target string) (string, error) {
+	if target == "" {
+		target = "."
+	}
+	_, oldAPI, err := loadAPI(from, target)
+	if err != nil {
+		return "", err
+	}
+	name, newAPI, err := loadAPI(to, target)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	renderDiffTo(&buffer, name, from, to, oldAPI, newAPI)
+	return strings.TrimSpace(buffer.String()), nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	url_Regexp         = regexp.MustCompile(urlRx)
+	orderedList_Regexp = regexp.MustCompile(`^[0-9]+[.)]`)
+	qualified_Regexp   = regexp.MustCompile(`\b([a-z][a-z0-9_]*)\.([A-Z][A-Za-z0-9_]*)\b`)
+)
+
+// escapeWord escapes a word of documentation text (see Renderer.Escape),
+// linking a qualified identifier of another package of the site, if any
+// (see SiteLinks). The brackets of a doc link ([c.C]) become the link.
+func escapeWord(word string, lineStart bool, spans *_codeSpans) string {
+	render := renderer()
+	if len(RenderStyle.SiteLinks) == 0 || spans.inCode || strings.Contains(word, "`") || url_Regexp.MatchString(word) {
+		return render.Escape(word, lineStart, spans)
+	}
+	match := qualified_Regexp.FindStringSubmatchIndex(word)
+	if match == nil {
+		return render.Escape(word, lineStart, spans)
+	}
+	target, exists := RenderStyle.SiteLinks[word[match[2]:match[3]]]
+	if !exists {
+		return render.Escape(word, lineStart, spans)
+	}
+	start, end := match[0], match[1]
+	if start > 0 && word[start-1] == '[' && end < len(word) && word[end] == ']' {
+		start, end = start-1, end+1
+	}
+
+	var buffer bytes.Buffer
+	if start > 0 {
+		buffer.WriteString(render.Escape(word[:start], lineStart, spans))
+	}
+	buffer.WriteString(render.Link(render.Escape(word[match[0]:match[1]], false, spans), target))
+	if end < len(word) {
+		buffer.WriteString(render.Escape(word[end:], false, spans))
+	}
+	return buffer.String()
+}
+
+// _codeSpans tracks the `code spans` of a paragraph across its words. Only a
+// run of backticks followed (later in the paragraph) by a run of the same
+// length opens a span, any other run is literal.
+type _codeSpans struct {
+	inCode  bool
+	literal []bool // Whether each run of backticks of the paragraph is literal
+}
+
+// codeSpansOf returns the code spans of a paragraph of text
+func codeSpansOf(text string) *_codeSpans {
+	runList := backtickRun_Regexp.FindAllString(text, -1)
+	spans := &_codeSpans{literal: make([]bool, len(runList))}
+	open := -1
+	for index, run := range runList {
+		if open >= 0 {
+			if len(run) == len(runList[open]) {
+				open = -1
+			} else {
+				spans.literal[index] = true
+			}
+			continue
+		}
+		spans.literal[index] = true
+		for _, later := range runList[index+1:] {
+			if len(later) == len(run) {
+				spans.literal[index] = false
+				open = index
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// backticks consumes the next run of backticks, reporting whether it is
+// literal (rather than opening or closing a span, which it toggles)
+func (self *_codeSpans) backticks() bool {
+	literal := len(self.literal) > 0 && self.literal[0]
+	if len(self.literal) > 0 {
+		self.literal = self.literal[1:]
+	}
+	if !literal {
+		self.inCode = !self.inCode
+	}
+	return literal
+}
+
+// escapeMarkdown escapes the characters of a single word that Markdown would
+// otherwise treat as markup: escapes (\), emphasis (*, _), HTML tags (<T>), table
+// cells (|), and, at the start of a line, headings, block quotes, list items, and
+// thematic breaks or setext underlines (---, ===).
+//
+// Code spans (tracked across words with spans) and URLs are left alone.
+// Github Flavored Markdown (gfm) has tables, and no intraword emphasis.
+func escapeMarkdown(word string, lineStart bool, spans *_codeSpans, gfm bool) string {
+	var buffer bytes.Buffer
+
+	if lineStart && !spans.inCode {
+		switch {
+		case word[0] == '#', word == "+", strings.Trim(word, "-") == "", strings.Trim(word, "=") == "":
+			// A run of - or = alone is a thematic break, or underlines the
+			// line before it (setext)
+			buffer.WriteByte('\\')
+		case word[0] == '>':
+			buffer.WriteString("&gt;")
+			word = word[1:]
+		case orderedList_Regexp.MatchString(word):
+			match := orderedList_Regexp.FindString(word)
+			buffer.WriteString(match[:len(match)-1])
+			buffer.WriteByte('\\')
+			buffer.WriteString(match[len(match)-1:])
+			word = word[len(match):]
+		}
+	}
+
+	skip := url_Regexp.FindAllStringIndex(word, -1)
+	for index := 0; index < len(word); index++ {
+		if len(skip) > 0 && index == skip[0][0] && !spans.inCode {
+			buffer.WriteString(word[skip[0][0]:skip[0][1]])
+			index = skip[0][1] - 1
+			skip = skip[1:]
+			continue
+		}
+		chr := word[index]
+		if chr == '`' {
+			literal := spans.backticks() && !spans.inCode
+			for ; index < len(word) && word[index] == '`'; index++ {
+				if literal {
+					buffer.WriteByte('\\')
+				}
+				buffer.WriteByte('`')
+			}
+			index--
+			continue
+		}
+		if spans.inCode {
+			buffer.WriteByte(chr)
+			continue
+		}
+		switch chr {
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '*':
+			buffer.WriteString(`\*`)
+		case '_':
+			if !gfm || !isIntraword(word, index) {
+				buffer.WriteString(`\_`)
+			} else {
+				buffer.WriteByte(chr)
+			}
+		case '<':
+			buffer.WriteString("&lt;")
+		case '|':
+			if !gfm {
+				buffer.WriteByte(chr)
+			} else {
+				buffer.WriteString(`\|`)
+			}
+		default:
+			buffer.WriteByte(chr)
+		}
+	}
+
+	return buffer.String()
+}
+
+// escapeAsciiDoc is the AsciiDoc counterpart of escapeMarkdown. AsciiDoc has no
+// general escape character, so the markup characters are replaced by their
+// character replacement attributes (or character references), and a line that
+// would start a section, list, or block is prefixed with {empty}.
+func escapeAsciiDoc(word string, lineStart bool, spans *_codeSpans) string {
+	var buffer bytes.Buffer
+
+	if lineStart && !spans.inCode {
+		switch {
+		case word[0] == '=', word[0] == '.', word == "*", word == "-", word[0] == '[',
+			orderedList_Regexp.MatchString(word):
+			buffer.WriteString("{empty}")
+		}
+	}
+
+	skip := url_Regexp.FindAllStringIndex(word, -1)
+	for index := 0; index < len(word); index++ {
+		if len(skip) > 0 && index == skip[0][0] && !spans.inCode {
+			buffer.WriteString(word[skip[0][0]:skip[0][1]])
+			index = skip[0][1] - 1
+			skip = skip[1:]
+			continue
+		}
+		chr := word[index]
+		if chr == '`' {
+			literal := spans.backticks() && !spans.inCode
+			for ; index < len(word) && word[index] == '`'; index++ {
+				if literal {
+					buffer.WriteString("&#96;")
+				} else {
+					buffer.WriteByte('`')
+				}
+			}
+			index--
+			continue
+		}
+		if spans.inCode {
+			buffer.WriteByte(chr)
+			continue
+		}
+		switch chr {
+		case '*':
+			buffer.WriteString("{asterisk}")
+		case '_':
+			if isIntraword(word, index) {
+				buffer.WriteByte(chr)
+			} else {
+				buffer.WriteString("&#95;")
+			}
+		case '#':
+			buffer.WriteString("&#35;")
+		case '+':
+			buffer.WriteString("{plus}")
+		case '^':
+			buffer.WriteString("{caret}")
+		case '~':
+			buffer.WriteString("{tilde}")
+		case '|':
+			buffer.WriteString("{vbar}")
+		default:
+			buffer.WriteByte(chr)
+		}
+	}
+
+	return buffer.String()
+}
+
+// escapeRST is the reStructuredText counterpart of escapeMarkdown. A `code span`
+// becomes an inline literal (a double backquoted span), since single backquotes are interpreted text.
+func escapeRST(word string, lineStart bool, spans *_codeSpans) string {
+	var buffer bytes.Buffer
+
+	if lineStart && !spans.inCode {
+		switch {
+		case word == "-", word == "*", word == "+", strings.HasPrefix(word, ".."),
+			strings.HasPrefix(word, "#."), strings.Trim(word, "=-~^") == "":
+			buffer.WriteByte('\\')
+		case orderedList_Regexp.MatchString(word):
+			match := orderedList_Regexp.FindString(word)
+			buffer.WriteString(match[:len(match)-1])
+			buffer.WriteByte('\\')
+			buffer.WriteString(match[len(match)-1:])
+			word = word[len(match):]
+		}
+	}
+
+	skip := url_Regexp.FindAllStringIndex(word, -1)
+	for index := 0; index < len(word); index++ {
+		if len(skip) > 0 && index == skip[0][0] && !spans.inCode {
+			buffer.WriteString(word[skip[0][0]:skip[0][1]])
+			index = skip[0][1] - 1
+			skip = skip[1:]
+			continue
+		}
+		chr := word[index]
+		if chr == '`' {
+			literal := spans.backticks()
+			start := index
+			for ; index < len(word) && word[index] == '`'; index++ {
+			}
+			switch {
+			case literal && spans.inCode:
+				buffer.WriteString(word[start:index])
+			case literal:
+				buffer.WriteString(strings.Repeat("\\`", index-start))
+			default:
+				buffer.WriteString("``")
+			}
+			index--
+			continue
+		}
+		if spans.inCode {
+			buffer.WriteByte(chr)
+			continue
+		}
+		switch chr {
+		case '\\', '*', '|':
+			buffer.WriteByte('\\')
+			buffer.WriteByte(chr)
+		case '_':
+			if isIntraword(word, index) {
+				buffer.WriteByte(chr)
+			} else {
+				buffer.WriteString(`\_`)
+			}
+		default:
+			buffer.WriteByte(chr)
+		}
+	}
+
+	return buffer.String()
+}
+
+// isIntraword reports whether the character at index is surrounded by
+// letters or digits (e.g. the underscore in snake_case), where Github
+// Flavored Markdown does not start emphasis.
+func isIntraword(word string, index int) bool {
+	before, _ := utf8.DecodeLastRuneInString(word[:index])
+	after, _ := utf8.DecodeRuneInString(word[index+1:])
+	return isAlphaNumeric(before) && isAlphaNumeric(after)
+}
+
+func isAlphaNumeric(chr rune) bool {
+	return chr != utf8.RuneError && (unicode.IsLetter(chr) || unicode.IsDigit(chr))
+}
+
+// escapeInline escapes a short run of documentation text (like a table
+// cell) that is not going through toText, honoring RenderStyle.
+func escapeInline(text string) string {
+	if !RenderStyle.EscapeMarkdown || RenderStyle.MarkdownComments {
+		return text
+	}
+	render := renderer()
+	spans := codeSpansOf(text)
+	wordList := strings.Fields(text)
+	for index, word := range wordList {
+		wordList[index] = render.Escape(word, false, spans)
+	}
+	return strings.Join(wordList, " ")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var exampleOutput_Regexp = regexp.MustCompile(`(?i)//[[:space:]]*(unordered )?output:`)
+
+// findExamples returns the examples of the tests of the package name, both
+// those of the package itself and of its external test package
+func findExamples(testSet map[string]*ast.Package, name string) []*doc.Example {
+	var fileList []*ast.File
+	for _, testName := range []string{name, name + "_test"} {
+		testPkg := testSet[testName]
+		if testPkg == nil {
+			continue
+		}
+		filenameList := make([]string, 0, len(testPkg.Files))
+		for filename := range testPkg.Files {
+			filenameList = append(filenameList, filename)
+		}
+		sort.Strings(filenameList)
+		for _, filename := range filenameList {
+			fileList = append(fileList, testPkg.Files[filename])
+		}
+	}
+	return doc.Examples(fileList...)
+}
+
+// classifyExamples gives each example to what it is an example of (by its
+// name, as go doc does): Example is of the package, ExampleF of the function
+// (or type) F, and ExampleT_M of the method M of type T. A suffix starting
+// with a lower-case letter (Example_suffix, ExampleF_suffix) distinguishes
+// several examples of the same thing. An example of nothing is dropped.
+func classifyExamples(pkg *doc.Package, list []*doc.Example) {
+	exampleMap := map[string]*[]*doc.Example{
+		"": &pkg.Examples,
+	}
+	for _, entry := range pkg.Funcs {
+		exampleMap[entry.Name] = &entry.Examples
+	}
+	for _, entry := range pkg.Types {
+		exampleMap[entry.Name] = &entry.Examples
+		for _, function := range entry.Funcs {
+			exampleMap[function.Name] = &function.Examples
+		}
+		for _, method := range entry.Methods {
+			exampleMap[entry.Name+"_"+method.Name] = &method.Examples
+		}
+	}
+
+	for _, example := range list {
+		name, suffix := example.Name, ""
+		if index := strings.LastIndex(name, "_"); index >= 0 {
+			if first, _ := utf8.DecodeRuneInString(name[index+1:]); unicode.IsLower(first) {
+				name, suffix = name[:index], name[index+1:]
+			}
+		}
+		if target := exampleMap[name]; target != nil {
+			example.Suffix = suffix
+			*target = append(*target, example)
+		}
+	}
+}
+
+// exampleCode returns the body of an example function (without the braces,
+// unindented, and without the output comment)
+func exampleCode(example *doc.Example) string {
+	code := sourceOfNode(&printer.CommentedNode{Node: example.Code, Comments: example.Comments})
+	if length := len(code); length >= 2 && code[0] == '{' && code[length-1] == '}' {
+		lineList := strings.Split(strings.Trim(code[1:length-1], "\n"), "\n")
+		for index, line := range lineList {
+			lineList[index] = strings.TrimPrefix(line, "\t")
+		}
+		code = strings.Join(lineList, "\n")
+		if location := exampleOutput_Regexp.FindStringIndex(code); location != nil {
+			code = code[:location[0]]
+		}
+	}
+	return strings.TrimSpace(code)
+}
+
+func renderExamplesTo(writer io.Writer, list []*doc.Example) {
+	if !RenderStyle.IncludeExamples {
+		return
+	}
+	render := renderer()
+	for _, example := range list {
+		title := "Example"
+		if example.Suffix != "" {
+			title += " (" + example.Suffix + ")"
+		}
+		fmt.Fprintf(writer, "%s\n", render.Paragraph(render.Emphasis(title+":")))
+		if example.Doc != "" {
+			fmt.Fprintf(writer, "%s\n", formatIndent(filterText(example.Doc)))
+		}
+		fmt.Fprintf(writer, "%s\n", indentCode(exampleCode(example)))
+		if output := strings.TrimSpace(example.Output); output != "" {
+			fmt.Fprintf(writer, "\n%s\n%s\n", render.Paragraph("Output:"), render.CodeBlock("", output))
+		}
+		fmt.Fprintf(writer, "\n")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// A hint line at the start of a preformatted block, e.g.:
+	//
+	//	```json
+	//	{ "name": "example" }
+	//
+	fenceHint_Regexp = regexp.MustCompile("^(?:```|~~~)[ \t]*([A-Za-z0-9_+-]+)[ \t]*\n?$")
+
+	// A complete fenced block, as emitted by _gfmRenderer.CodeBlock
+	fence_Regexp = regexp.MustCompile(fencePattern(3, 16))
+
+	backtickRun_Regexp = regexp.MustCompile("`+")
+)
+
+// fencePattern matches a fenced block of from minimum to maximum backticks,
+// which is closed by a fence of the same length (as Go regular expressions
+// have no backreferences, one alternative per length, the longest first)
+func fencePattern(minimum, maximum int) string {
+	alternativeList := []string{}
+	for length := maximum; length >= minimum; length-- {
+		fence := fmt.Sprintf("`{%d}", length)
+		alternativeList = append(alternativeList, fence+"[^`\n]*\n.*?^"+fence)
+	}
+	return "(?ms)^(?:" + strings.Join(alternativeList, "|") + ")[ \t]*$"
+}
+
+// codeFence returns a fence of backticks for source, longer than
+// any run of backticks in it (and at least three)
+func codeFence(source string) string {
+	length := 3
+	for _, run := range backtickRun_Regexp.FindAllString(source, -1) {
+		if len(run) >= length {
+			length = len(run) + 1
+		}
+	}
+	return strings.Repeat("`", length)
+}
+
+// fenceLanguage guesses the language of a preformatted block of
+// (unindented) lines, returning the remaining lines and the language
+// ("" if unknown). An explicit hint line always wins.
+func fenceLanguage(lines []string) ([]string, string) {
+	if len(lines) > 0 {
+		if match := fenceHint_Regexp.FindStringSubmatch(lines[0]); match != nil {
+			return lines[1:], match[1]
+		}
+	}
+
+	source := strings.Join(lines, "")
+	switch {
+	case isShellSource(lines):
+		return lines, "sh"
+	case isJSONSource(source):
+		return lines, "json"
+	case isGoSource(source):
+		return lines, "go"
+	}
+	return lines, ""
+}
+
+// isShellSource reports whether the first line that is not a "#" comment
+// looks like a shell prompt ("$ command").
+func isShellSource(lines []string) bool {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "$ ")
+	}
+	return false
+}
+
+func isJSONSource(source string) bool {
+	source = strings.TrimSpace(source)
+	if source == "" || (source[0] != '{' && source[0] != '[') {
+		return false
+	}
+	return json.Valid([]byte(source))
+}
+
+// isGoSource reports whether source parses as a Go file, as a list of
+// declarations, or as a list of statements, having at least one declaration
+// or statement: a lone word parses as an expression, but isn't Go.
+func isGoSource(source string) bool {
+	if strings.TrimSpace(source) == "" {
+		return false
+	}
+	for _, wrapped := range []string{
+		source,
+		"package _\n" + source,
+	} {
+		file, err := parser.ParseFile(token.NewFileSet(), "", wrapped, 0)
+		if err == nil {
+			return len(file.Decls) > 0
+		}
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package _\nfunc _() {\n"+source+"\n}", 0)
+	if err != nil {
+		return false
+	}
+	for _, statement := range file.Decls[0].(*ast.FuncDecl).Body.List {
+		if isStatement(statement) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStatement reports whether statement is something other than a bare
+// expression: Go only allows a call or a receive as an expression statement
+func isStatement(statement ast.Stmt) bool {
+	switch statement := statement.(type) {
+	case *ast.EmptyStmt:
+		return false
+	case *ast.LabeledStmt:
+		return isStatement(statement.Stmt)
+	case *ast.ExprStmt:
+		switch expression := statement.X.(type) {
+		case *ast.CallExpr:
+			return true
+		case *ast.UnaryExpr:
+			return expression.Op == token.ARROW
+		}
+		return false
+	}
+	return true
+}
+
+// writeCodeBlock writes a preformatted block as a code block (a fenced
+// code block for Github Flavored Markdown), labelled with its language
+// (if we can guess it).
+func writeCodeBlock(w io.Writer, lines []string) {
+	lines, language := fenceLanguage(lines)
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	code := renderer().CodeBlock(language, strings.TrimRight(strings.Join(lines, ""), " \t\n"))
+	io.WriteString(w, code)
+	if !strings.HasSuffix(code, "\n") {
+		w.Write(nl)
+	}
+}
+
+// outsideFence applies fn to every part of target that is not
+// inside a fenced code block (or a code block of the output format).
+func outsideFence(target string, fn func(string) string) string {
+	var result []string
+	last := 0
+	for _, match := range renderer().CodeBlockRegexp().FindAllStringIndex(target, -1) {
+		result = append(result, fn(target[last:match[0]]), target[match[0]:match[1]])
+		last = match[1]
+	}
+	result = append(result, fn(target[last:]))
+	return strings.Join(result, "")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type _field struct {
+	name     string
+	kind     string
+	tag      string
+	doc      string
+	embedded bool
+	pos      token.Pos
+}
+
+// fieldsOf returns the (exported) fields of a struct type, in declaration order.
+func fieldsOf(entry *doc.Type) []_field {
+	spec := typeSpecOf(entry)
+	if spec == nil {
+		return nil
+	}
+	structType, ok := spec.Type.(*ast.StructType)
+	if !ok || structType.Fields == nil {
+		return nil
+	}
+
+	var fieldList []_field
+	for _, field := range structType.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		text := field.Doc.Text()
+		if text == "" {
+			text = field.Comment.Text()
+		}
+		text = strings.Join(strings.Fields(filterText(text)), " ")
+		kind := sourceOfNode(field.Type)
+
+		if len(field.Names) == 0 {
+			// Embedded, the name is the name of the type
+			name := strings.TrimPrefix(kind, "*")
+			if index := strings.LastIndex(name, "."); index >= 0 {
+				name = name[index+1:]
+			}
+			if index := strings.Index(name, "["); index >= 0 {
+				name = name[:index]
+			}
+			fieldList = append(fieldList, _field{name: name, kind: kind, tag: tag, doc: text, embedded: true, pos: field.Pos()})
+			continue
+		}
+		for _, name := range field.Names {
+			fieldList = append(fieldList, _field{name: name.Name, kind: kind, tag: tag, doc: text, pos: name.Pos()})
+		}
+	}
+	return fieldList
+}
+
+// tableCell escapes documentation text for a table cell; escapeInline
+// already takes care of "|" when it's escaping.
+func tableCell(text string) string {
+	if !RenderStyle.EscapeMarkdown || RenderStyle.MarkdownComments {
+		return renderer().TableCell(text)
+	}
+	return escapeInline(text)
+}
+
+// renderFieldsTo renders the fields of a struct type as a table (which is a list
+// in standard Markdown, since it doesn't have tables).
+func renderFieldsTo(writer io.Writer, entry *doc.Type) {
+	if !RenderStyle.IncludeFields {
+		return
+	}
+	fieldList := fieldsOf(entry)
+	if len(fieldList) == 0 {
+		return
+	}
+
+	render := renderer()
+	var rowList [][]string
+	for _, field := range fieldList {
+		name := field.name
+		if field.embedded {
+			name = render.Emphasis(name) + " (embedded)"
+		}
+		rowList = append(rowList, []string{
+			render.TableCell(name),
+			render.TableCell(render.CodeSpan(field.kind)),
+			render.TableCell(render.CodeSpan(field.tag)),
+			tableCell(field.doc),
+		})
+	}
+	fmt.Fprintf(writer, "%s", render.Table([]string{"Field", "Type", "Tag", "Description"}, rowList))
+	fmt.Fprintf(writer, "\n")
+}
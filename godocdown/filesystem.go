@@ -0,0 +1,326 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readFS reads the file name in directory of fsys
+func readFS(fsys fs.FS, directory, name string) ([]byte, error) {
+	return fs.ReadFile(fsys, path.Join(directory, name))
+}
+
+// parseDir parses the Go files in directory of fsys (either the tests, or
+// everything else), like parser.ParseDir, naming each file after prefix
+// (rather than directory).
+func parseDir(fsys fs.FS, directory, prefix string, tests bool) (map[string]*ast.Package, error) {
+	entryList, err := fs.ReadDir(fsys, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgSet := map[string]*ast.Package{}
+	for _, entry := range entryList {
+		name := entry.Name()
+		if entry.IsDir() || name[0] == '.' || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") != tests {
+			continue
+		}
+		source, err := readFS(fsys, directory, name)
+		if err != nil {
+			return nil, err
+		}
+		filename := filepath.Join(prefix, name)
+		file, err := parser.ParseFile(fset, filename, source, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		pkg := pkgSet[file.Name.Name]
+		if pkg == nil {
+			pkg = &ast.Package{
+				Name:  file.Name.Name,
+				Files: map[string]*ast.File{},
+			}
+			pkgSet[pkg.Name] = pkg
+		}
+		pkg.Files[filename] = file
+	}
+	return pkgSet, nil
+}
+
+// findTemplateFS is findTemplate for directory of fsys, returning the name of the template
+func findTemplateFS(fsys fs.FS, directory string) string {
+	for _, templateName := range templateNameList {
+		_, err := fs.Stat(fsys, path.Join(directory, templateName))
+		if err == nil {
+			return templateName
+		}
+	}
+	return ""
+}
+
+// splitArchive splits a target of the form "archive.zip/directory" (or just
+// "archive.zip") into the archive and the (slash-separated) directory within it.
+// The archive is the first path prefix ending in ".zip" that is a file, so a
+// directory like "a.zip.d" (or even "a.zip") on the way to it is passed over.
+func splitArchive(target string) (string, string, bool) {
+	componentList := strings.Split(filepath.ToSlash(target), "/")
+	for index, component := range componentList {
+		if !strings.HasSuffix(component, ".zip") {
+			continue
+		}
+		archive := filepath.FromSlash(strings.Join(componentList[:index+1], "/"))
+		if info, err := os.Stat(archive); err != nil || info.IsDir() {
+			continue
+		}
+		directory := path.Clean("/" + strings.Join(componentList[index+1:], "/"))[1:]
+		if directory == "" {
+			directory = "."
+		}
+		return archive, directory, true
+	}
+	return "", "", false
+}
+
+// moduleRoot returns the directory everything in a module zip (as from a
+// module proxy) is under, "<module>@<version>", or "" for any other zip.
+func moduleRoot(reader *zip.Reader) string {
+	root := ""
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue // A directory
+		}
+		at := strings.Index(file.Name, "@")
+		if at < 0 {
+			return ""
+		}
+		slash := strings.Index(file.Name[at:], "/")
+		if slash < 0 {
+			return ""
+		}
+		prefix := file.Name[:at+slash]
+		if root == "" {
+			root = prefix
+		} else if prefix != root {
+			return ""
+		}
+	}
+	return root
+}
+
+// loadArchive loads the package in directory of a zip. In a module zip,
+// directory is relative to the module, which gives the import path.
+func loadArchive(archive, directory string) (*_document, error) {
+	// The archive stays open: the document goes on to read its template
+	// (and go.mod, LICENSE, ...) from it after loading.
+	readCloser, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read \"%s\": %v", archive, err)
+	}
+	reader := &readCloser.Reader
+
+	var fsys fs.FS = reader
+	importPath := ""
+	if root := moduleRoot(reader); root != "" {
+		fsys, err = fs.Sub(reader, root)
+		if err != nil {
+			return nil, err
+		}
+		importPath = path.Join(root[:strings.LastIndex(root, "@")], directory)
+	}
+
+	return loadDocumentFS(fsys, directory, &build.Package{
+		Dir:        filepath.Join(archive, filepath.FromSlash(directory)),
+		ImportPath: importPath,
+	})
+}
+
+// _gitTree is the tree of a git revision (a commit, tag, or branch) as an
+// fs.FS, read with git as needed rather than checked out. Names are relative
+// to the top of the repository.
+type _gitTree struct {
+	top      string
+	revision string
+}
+
+// newGitTree returns the tree of revision of the repository directory is in,
+// along with the (slash-separated) directory within it.
+func newGitTree(directory, revision string) (*_gitTree, string, error) {
+	if _, err := git(directory, "rev-parse", "--verify", revision+"^{tree}"); err != nil {
+		return nil, "", err
+	}
+	top, err := git(directory, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", err
+	}
+	prefix, err := git(directory, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, "", err
+	}
+	directory = path.Clean("/" + strings.TrimSpace(string(prefix)))[1:]
+	if directory == "" {
+		directory = "."
+	}
+	return &_gitTree{
+		top:      strings.TrimSpace(string(top)),
+		revision: revision,
+	}, directory, nil
+}
+
+// object names the object at name in the tree, for git.
+func (self *_gitTree) object(name string) string {
+	if name == "." {
+		return self.revision + ":"
+	}
+	return self.revision + ":" + name
+}
+
+func (self *_gitTree) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	// <mode> SP <type> SP <object> SP <size> TAB <file> NUL
+	output, err := git(self.top, "ls-tree", "-z", "-l", self.object(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entryList := []fs.DirEntry{}
+	for _, entry := range strings.Split(string(output), "\x00") {
+		fields := strings.SplitN(entry, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		info := &_gitFileInfo{name: fields[1]}
+		attributeList := strings.Fields(fields[0])
+		if len(attributeList) != 4 {
+			continue
+		}
+		switch attributeList[1] {
+		case "tree":
+			info.mode = fs.ModeDir | 0555
+		case "blob":
+			info.mode = 0444
+			if attributeList[0] == "100755" {
+				info.mode = 0555
+			} else if attributeList[0] == "120000" {
+				info.mode = fs.ModeSymlink | 0444
+			}
+			info.size, _ = strconv.ParseInt(attributeList[3], 10, 64)
+		default:
+			continue // A submodule
+		}
+		entryList = append(entryList, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entryList, func(i, j int) bool {
+		return entryList[i].Name() < entryList[j].Name()
+	})
+	return entryList, nil
+}
+
+func (self *_gitTree) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &_gitFileInfo{name: ".", mode: fs.ModeDir | 0555}, nil
+	}
+	entryList, err := self.ReadDir(path.Dir(name))
+	if err == nil {
+		for _, entry := range entryList {
+			if entry.Name() == path.Base(name) {
+				return entry.Info()
+			}
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (self *_gitTree) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	content, err := git(self.top, "cat-file", "blob", self.object(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return content, nil
+}
+
+func (self *_gitTree) Open(name string) (fs.File, error) {
+	info, err := self.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		entryList, err := self.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &_gitDirectory{info: info, entryList: entryList}, nil
+	}
+	content, err := self.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &_gitFile{Reader: bytes.NewReader(content), info: info}, nil
+}
+
+type _gitFileInfo struct {
+	name string
+	mode fs.FileMode
+	size int64
+}
+
+func (self *_gitFileInfo) Name() string       { return self.name }
+func (self *_gitFileInfo) Size() int64        { return self.size }
+func (self *_gitFileInfo) Mode() fs.FileMode  { return self.mode }
+func (self *_gitFileInfo) ModTime() time.Time { return time.Time{} }
+func (self *_gitFileInfo) IsDir() bool        { return self.mode.IsDir() }
+func (self *_gitFileInfo) Sys() interface{}   { return nil }
+
+type _gitFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (self *_gitFile) Stat() (fs.FileInfo, error) { return self.info, nil }
+func (self *_gitFile) Close() error               { return nil }
+
+type _gitDirectory struct {
+	info      fs.FileInfo
+	entryList []fs.DirEntry
+}
+
+func (self *_gitDirectory) Stat() (fs.FileInfo, error) { return self.info, nil }
+func (self *_gitDirectory) Close() error               { return nil }
+
+func (self *_gitDirectory) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: self.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (self *_gitDirectory) ReadDir(count int) ([]fs.DirEntry, error) {
+	entryList := self.entryList
+	if count > 0 {
+		if len(entryList) == 0 {
+			return nil, io.EOF
+		}
+		if count < len(entryList) {
+			entryList = entryList[:count]
+		}
+	}
+	self.entryList = self.entryList[len(entryList):]
+	return entryList, nil
+}
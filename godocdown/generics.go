@@ -0,0 +1,128 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc"
+	"strings"
+)
+
+// typeParamsOf returns the type parameter list of a generic function or type
+// as it appears in the source, e.g. "[K comparable, V any]", or "" if there are none.
+func typeParamsOf(list *ast.FieldList) string {
+	if list == nil || len(list.List) == 0 {
+		return ""
+	}
+	fieldList := make([]string, 0, len(list.List))
+	for _, field := range list.List {
+		nameList := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			nameList = append(nameList, name.Name)
+		}
+		fieldList = append(fieldList, strings.Join(nameList, ", ")+" "+sourceOfNode(field.Type))
+	}
+	return "[" + strings.Join(fieldList, ", ") + "]"
+}
+
+// typeSpecOf returns the declaration of the given type.
+func typeSpecOf(entry *doc.Type) *ast.TypeSpec {
+	if entry.Decl == nil {
+		return nil
+	}
+	for _, spec := range entry.Decl.Specs {
+		if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.Name == entry.Name {
+			return spec
+		}
+	}
+	return nil
+}
+
+func funcTypeParams(entry *doc.Func) string {
+	if entry.Decl == nil {
+		return ""
+	}
+	return typeParamsOf(entry.Decl.Type.TypeParams)
+}
+
+func typeTypeParams(entry *doc.Type) string {
+	spec := typeSpecOf(entry)
+	if spec == nil {
+		return ""
+	}
+	return typeParamsOf(spec.TypeParams)
+}
+
+// splitConstraints separates the interfaces that are constraints (they
+// have type set elements like ~int | string, or are used as a constraint
+// by a type parameter in the package) from the other types.
+func splitConstraints(pkg *doc.Package) (typeList, constraintList []*doc.Type) {
+	used := map[string]bool{}
+	markUsed := func(list *ast.FieldList) {
+		if list == nil {
+			return
+		}
+		for _, field := range list.List {
+			ast.Inspect(field.Type, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.SelectorExpr:
+					// Of another package (e.g. constraints.Ordered), not of this one
+					return false
+				case *ast.Ident:
+					used[node.Name] = true
+				}
+				return true
+			})
+		}
+	}
+	markFuncs := func(list []*doc.Func) {
+		for _, entry := range list {
+			if entry.Decl != nil {
+				markUsed(entry.Decl.Type.TypeParams)
+			}
+		}
+	}
+	markFuncs(pkg.Funcs)
+	for _, entry := range pkg.Types {
+		if spec := typeSpecOf(entry); spec != nil {
+			markUsed(spec.TypeParams)
+		}
+		markFuncs(entry.Funcs)
+		markFuncs(entry.Methods)
+	}
+
+	for _, entry := range pkg.Types {
+		spec := typeSpecOf(entry)
+		if spec == nil {
+			typeList = append(typeList, entry)
+			continue
+		}
+		if iface, ok := spec.Type.(*ast.InterfaceType); ok && (used[entry.Name] || hasTypeSet(iface)) {
+			constraintList = append(constraintList, entry)
+			continue
+		}
+		typeList = append(typeList, entry)
+	}
+	return
+}
+
+// hasTypeSet reports whether the interface has an element that is not a
+// method or an embedded interface name, which means it can only be a constraint.
+func hasTypeSet(iface *ast.InterfaceType) bool {
+	if iface.Methods == nil {
+		return false
+	}
+	for _, field := range iface.Methods.List {
+		if len(field.Names) > 0 {
+			continue // A method
+		}
+		switch field.Type.(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+			// An embedded interface (or a single type, which we can't tell apart without type checking)
+			if ident, ok := field.Type.(*ast.Ident); ok && ident.Name == "comparable" {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -208,40 +208,48 @@ func blocks(text string) []block {
 	return out
 }
 
+const (
+	// wrapNone emits each paragraph as a single line
+	wrapNone = 0
+	// wrapLines keeps the line breaks of the original comment
+	wrapLines = -1
+)
+
 // ToText prepares comment text for presentation in textual output.
 // It wraps paragraphs of text to width or fewer Unicode code points
 // and then prefixes each line with the indent.  In preformatted sections
-// (such as program text), it prefixes each non-blank line with preIndent.
-func toText(w io.Writer, text string, indent, preIndent string, width int) {
+// (such as program text), it emits a code block of the output format
+// (see Renderer.CodeBlock).
+// A width of wrapNone or wrapLines disables wrapping.
+func toText(w io.Writer, text string, indent string, width int) {
 	l := lineWrapper{
 		out:    w,
 		width:  width,
 		indent: indent,
+		escape: RenderStyle.EscapeMarkdown,
 	}
 	for _, b := range blocks(text) {
 		switch b.op {
 		case opPara:
+			l.spans = codeSpansOf(strings.Join(b.lines, ""))
 			// l.write will add leading newline if required
 			for _, line := range b.lines {
 				l.write(line)
 			}
 			l.flush()
 		case opHead:
+			// A heading is intentional, so leave it unescaped
+			escape := l.escape
+			l.escape = false
 			w.Write(nl)
 			for _, line := range b.lines {
 				l.write(line + "\n")
 			}
 			l.flush()
+			l.escape = escape
 		case opPre:
 			w.Write(nl)
-			for _, line := range b.lines {
-				if !isBlank(line) {
-					w.Write([]byte(preIndent))
-					w.Write([]byte(line))
-				} else {
-					w.Write([]byte(line))
-				}
-			}
+			writeCodeBlock(w, b.lines)
 		}
 	}
 }
@@ -253,21 +261,25 @@ type lineWrapper struct {
 	indent    string
 	n         int
 	pendSpace int
+	broken    bool        // The last line was ended by wrapLines, not by a paragraph break
+	escape    bool        // Escape Markdown-significant characters
+	spans     *_codeSpans // The `code spans` of the paragraph (which may cross words)
 }
 
 var nl = []byte("\n")
 var space = []byte(" ")
 
 func (l *lineWrapper) write(text string) {
-	if l.n == 0 && l.printed {
+	if l.n == 0 && l.printed && !l.broken {
 		l.out.Write(nl) // blank line before new paragraph
 	}
 	l.printed = true
+	l.broken = false
 
 	for _, f := range strings.Fields(text) {
 		w := utf8.RuneCountInString(f)
 		// wrap if line is too long
-		if l.n > 0 && l.n+l.pendSpace+w > l.width {
+		if l.width > 0 && l.n > 0 && l.n+l.pendSpace+w > l.width {
 			l.out.Write(nl)
 			l.n = 0
 			l.pendSpace = 0
@@ -276,13 +288,26 @@ func (l *lineWrapper) write(text string) {
 			l.out.Write([]byte(l.indent))
 		}
 		l.out.Write(space[:l.pendSpace])
-		l.out.Write([]byte(f))
+		if l.escape {
+			l.out.Write([]byte(escapeWord(f, l.n == 0, l.spans)))
+		} else {
+			l.out.Write([]byte(f))
+		}
 		l.n += l.pendSpace + w
 		l.pendSpace = 1
 	}
+
+	if l.width == wrapLines && l.n > 0 {
+		// keep the line break from the source
+		l.out.Write(nl)
+		l.n = 0
+		l.pendSpace = 0
+		l.broken = true
+	}
 }
 
 func (l *lineWrapper) flush() {
+	l.broken = false
 	if l.n == 0 {
 		return
 	}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+)
+
+// _implements records which interfaces each type of a package implements, and
+// (the inverse) which types implement each interface declared in the package.
+type _implements struct {
+	implements    map[string][]_implementsEntry
+	implementedBy map[string][]_implementsEntry
+	errorList     []error  // Of type checking, which may have left out results
+	genericList   []string // Generic types, which are left out
+}
+
+// An interface (or a type), and the pointer type that does the implementing (if any)
+type _implementsEntry struct {
+	name string
+	as   string
+}
+
+// A selection of standard library interfaces that are worth mentioning.
+// These are built by hand so that we don't have to import (and type check) their packages.
+var standardInterfaceList = func() []struct {
+	name  string
+	iface *types.Interface
+} {
+	byteSlice := types.NewSlice(types.Typ[types.Byte])
+	errorType := types.Universe.Lookup("error").Type()
+	variable := func(typ types.Type) *types.Var {
+		return types.NewVar(token.NoPos, nil, "", typ)
+	}
+	method := func(name string, params, results []*types.Var) *types.Func {
+		signature := types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), types.NewTuple(results...), false)
+		return types.NewFunc(token.NoPos, nil, name, signature)
+	}
+	newInterface := func(methodList ...*types.Func) *types.Interface {
+		return types.NewInterfaceType(methodList, nil).Complete()
+	}
+	return []struct {
+		name  string
+		iface *types.Interface
+	}{
+		{"error", errorType.Underlying().(*types.Interface)},
+		{"fmt.Stringer", newInterface(method("String", nil, []*types.Var{variable(types.Typ[types.String])}))},
+		{"io.Reader", newInterface(method("Read", []*types.Var{variable(byteSlice)}, []*types.Var{variable(types.Typ[types.Int]), variable(errorType)}))},
+		{"io.Writer", newInterface(method("Write", []*types.Var{variable(byteSlice)}, []*types.Var{variable(types.Typ[types.Int]), variable(errorType)}))},
+		{"io.Closer", newInterface(method("Close", nil, []*types.Var{variable(errorType)}))},
+		{"json.Marshaler", newInterface(method("MarshalJSON", nil, []*types.Var{variable(byteSlice), variable(errorType)}))},
+	}
+}()
+
+// checkImplements type checks the package (which must not have been
+// trimmed by doc.New yet) and works out which of its exported types
+// implement which interfaces. Type errors (like an import that the source
+// importer can't resolve) are reported, but we do what we can with the
+// information we have. Generic types are left out (and reported).
+func checkImplements(fset *token.FileSet, pkg *ast.Package) *_implements {
+	fileList := make([]*ast.File, 0, len(pkg.Files))
+	nameList := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+	for _, name := range nameList {
+		fileList = append(fileList, pkg.Files[name])
+	}
+
+	var errorList []error
+	config := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			errorList = append(errorList, err)
+		},
+	}
+	checked, _ := config.Check(pkg.Name, fset, fileList, nil)
+	if len(errorList) > 0 {
+		more := ""
+		if len(errorList) > 1 {
+			more = fmt.Sprintf(" (and %d more)", len(errorList)-1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: -implements may be incomplete, type checking %s failed: %v%s\n", pkg.Name, errorList[0], more)
+	}
+	if checked == nil {
+		return nil
+	}
+
+	type _interface struct {
+		name  string
+		iface *types.Interface
+	}
+	var interfaceList []_interface
+	var namedList []*types.Named
+	var genericList []string
+	for _, name := range checked.Scope().Names() {
+		object, ok := checked.Scope().Lookup(name).(*types.TypeName)
+		if !ok || !object.Exported() || object.IsAlias() {
+			continue
+		}
+		named, ok := object.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if named.TypeParams().Len() > 0 {
+			// What a generic type implements depends on how it is instantiated
+			genericList = append(genericList, name)
+			continue
+		}
+		if iface, ok := named.Underlying().(*types.Interface); ok {
+			if iface.IsMethodSet() && iface.NumMethods() > 0 {
+				interfaceList = append(interfaceList, _interface{name, iface})
+			}
+			continue
+		}
+		namedList = append(namedList, named)
+	}
+	if len(genericList) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: -implements leaves out the generic types of %s: %s\n", pkg.Name, strings.Join(genericList, ", "))
+	}
+	for _, standard := range standardInterfaceList {
+		interfaceList = append(interfaceList, _interface{standard.name, standard.iface})
+	}
+
+	result := &_implements{
+		implements:    map[string][]_implementsEntry{},
+		implementedBy: map[string][]_implementsEntry{},
+		errorList:     errorList,
+		genericList:   genericList,
+	}
+	for _, named := range namedList {
+		name := named.Obj().Name()
+		for _, iface := range interfaceList {
+			implementer := ""
+			switch {
+			case types.Implements(named, iface.iface):
+				implementer = name
+				result.implements[name] = append(result.implements[name], _implementsEntry{name: iface.name})
+			case types.Implements(types.NewPointer(named), iface.iface):
+				implementer = "*" + name
+				result.implements[name] = append(result.implements[name], _implementsEntry{name: iface.name, as: implementer})
+			}
+			if implementer != "" && !strings.Contains(iface.name, ".") && iface.name != "error" {
+				result.implementedBy[iface.name] = append(result.implementedBy[iface.name], _implementsEntry{name: implementer})
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// renderInstallTo renders how to install the package (from its module,
+// see findModule): go get for a library, go install for a command, and
+// the minimum version of Go (the go directive of its go.mod)
+func renderInstallTo(writer io.Writer, document *_document) {
+	module := findModule(document)
+	if module == nil || module.path == "" {
+		return
+	}
+	render := renderer()
+
+	command := "go get " + module.path + "@latest"
+	if document.IsCommand {
+		importPath := resolveImportPath(document, module)
+		if importPath == "" {
+			return
+		}
+		command = "go install " + importPath + "@latest"
+	}
+	fmt.Fprintf(writer, "%s\n\n%s\n\n", formatHeading(RenderStyle.UsageHeader, "Installation"), render.CodeBlock("sh", command))
+	if module.goVersion != "" {
+		fmt.Fprintf(writer, "%s\n", render.Paragraph(fmt.Sprintf("Requires Go %s or later.", module.goVersion)))
+	}
+}
+
+// renderDependenciesTo lists the direct dependencies of the module of the
+// package (those of its go.mod not marked "// indirect"), with their versions
+func renderDependenciesTo(writer io.Writer, document *_document) {
+	module := findModule(document)
+	if module == nil {
+		return
+	}
+	render := renderer()
+
+	var itemList []string
+	for _, require := range module.requireList {
+		if !require.indirect {
+			itemList = append(itemList, render.CodeSpan(require.path)+" "+require.version)
+		}
+	}
+	if len(itemList) == 0 {
+		return
+	}
+	fmt.Fprintf(writer, "%s\n\n%s\n", formatHeading(RenderStyle.UsageHeader, "Dependencies"), render.List(itemList))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The documentation coverage of a package (see -lint)
+type _lintReport struct {
+	name       string
+	total      int
+	documented int
+	issueList  []string
+}
+
+func (self *_lintReport) coverage() float64 {
+	if self.total == 0 {
+		return 100
+	}
+	return 100 * float64(self.documented) / float64(self.total)
+}
+
+// lintDocument checks the documentation of every exported symbol that
+// renderUsageTo would emit (and the package comment). This has to be done
+// before the next loadDocument, since positions come from the (global) fset.
+func lintDocument(document *_document) *_lintReport {
+	report := &_lintReport{name: document.Name}
+	base, _ := os.Getwd()
+
+	issue := func(pos token.Pos, format string, arguments ...interface{}) {
+		position := fset.Position(pos)
+		filename := position.Filename
+		if relative, err := filepath.Rel(base, filename); err == nil && !strings.HasPrefix(relative, "..") {
+			filename = relative
+		}
+		report.issueList = append(report.issueList, fmt.Sprintf("%s:%d: %s", filename, position.Line, fmt.Sprintf(format, arguments...)))
+	}
+	// check counts a symbol, which should be documented by a comment starting with
+	// its name (or "A name", "An name", "The name"), unless name is ""
+	check := func(pos token.Pos, what, name, text string) {
+		report.total++
+		if strings.TrimSpace(text) == "" {
+			issue(pos, "%s: missing doc comment", what)
+			return
+		}
+		report.documented++
+		if name != "" && !startsWithName(text, name) {
+			issue(pos, "%s: comment should start with \"%s\"", what, name)
+		}
+	}
+
+	report.total++
+	if strings.TrimSpace(document.pkg.Doc) == "" {
+		report.issueList = append(report.issueList, fmt.Sprintf("package %s: missing package comment", document.pkg.Name))
+	} else {
+		report.documented++
+	}
+	if document.IsCommand {
+		return report
+	}
+
+	values := func(list []*doc.Value) {
+		for _, entry := range list {
+			var nameList []string
+			for _, spec := range entry.Decl.Specs {
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range valueSpec.Names {
+						if name.IsExported() {
+							nameList = append(nameList, name.Name)
+						}
+					}
+				}
+			}
+			if len(nameList) == 0 {
+				continue
+			}
+			what := entry.Decl.Tok.String() + " " + strings.Join(nameList, ", ")
+			if len(nameList) == 1 {
+				check(entry.Decl.Pos(), what, nameList[0], entry.Doc)
+			} else {
+				// A group is documented as a whole
+				check(entry.Decl.Pos(), what, "", entry.Doc)
+			}
+		}
+	}
+	functions := func(list []*doc.Func) {
+		for _, entry := range list {
+			what := "func " + entry.Name
+			if entry.Recv != "" {
+				what = fmt.Sprintf("func (%s) %s", entry.Recv, entry.Name)
+			}
+			check(entry.Decl.Pos(), what, entry.Name, entry.Doc)
+		}
+	}
+
+	values(document.pkg.Consts)
+	values(document.pkg.Vars)
+	functions(document.pkg.Funcs)
+	for _, entry := range document.pkg.Types {
+		check(entry.Decl.Pos(), "type "+entry.Name, entry.Name, entry.Doc)
+		for _, field := range fieldsOf(entry) {
+			check(field.pos, "field "+entry.Name+"."+field.name, "", field.doc)
+		}
+		values(entry.Consts)
+		values(entry.Vars)
+		functions(entry.Funcs)
+		functions(entry.Methods)
+	}
+	return report
+}
+
+// startsWithName reports whether a doc comment starts with name, as a word.
+func startsWithName(text, name string) bool {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{"", "A ", "An ", "The "} {
+		if !strings.HasPrefix(text, prefix+name) {
+			continue
+		}
+		rest := strings.TrimPrefix(text, prefix+name)
+		if rest == "" || !isAlphaNumeric([]rune(rest)[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func renderLintTo(writer io.Writer, reportList []*_lintReport) {
+	for _, report := range reportList {
+		for _, issue := range report.issueList {
+			fmt.Fprintf(writer, "%s\n", issue)
+		}
+		fmt.Fprintf(writer, "%s: %.1f%% documented (%d/%d)\n", report.name, report.coverage(), report.documented, report.total)
+	}
+}
+
+// emitLint checks the documentation of the target package (or, given
+// "path/...", of every package under path), reporting whether the coverage of
+// every package is at least threshold (a percentage).
+func emitLint(target string, threshold float64) (string, bool, error) {
+	directoryList := []string{target}
+	if strings.HasSuffix(target, "...") {
+		root := filepath.Clean(strings.TrimSuffix(strings.TrimSuffix(target, "..."), "/"))
+		var err error
+		directoryList, err = findPackageDirectories(root, "")
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	var reportList []*_lintReport
+	pass := true
+	for _, directory := range directoryList {
+		document, err := loadDocument(directory)
+		if err != nil {
+			return "", false, err
+		}
+		if document == nil {
+			if len(directoryList) == 1 {
+				return "", false, fmt.Errorf("Could not find package: %s", target)
+			}
+			continue
+		}
+		report := lintDocument(document)
+		if len(directoryList) > 1 {
+			report.name = filepath.ToSlash(directory)
+		}
+		if report.coverage() < threshold {
+			pass = false
+		}
+		reportList = append(reportList, report)
+	}
+
+	var buffer bytes.Buffer
+	renderLintTo(&buffer, reportList)
+	return strings.TrimSpace(buffer.String()), pass, nil
+}
@@ -10,6 +10,14 @@ Command godocdown generates Go documentation in a GitHub-friendly Markdown forma
                                                                                  
     # Generate standard Markdown                                                 
     $ godocdown -plain .                                                         
+    
+    # Generate documentation for a single file, or for source on stdin
+    $ godocdown example.go > README.markdown
+    $ godocdown - < example.go > README.markdown
+    
+    # Generate documentation for a package in a zip of a module (as downloaded
+    # from a module proxy), which gives the import path
+    $ godocdown v1.2.3.zip/path/to/package > README.markdown
 
 This program is targeted at providing nice-looking documentation for GitHub. With this in
 mind, it generates GitHub Flavored Markdown (http://github.github.com/github-flavored-markdown/) by
@@ -28,6 +36,43 @@ Usage
     -output=""                                                                       
         Write output to a file instead of stdout                                     
         Write to stdout with -                                                       
+        The file is written atomically (to a temporary file that is renamed
+        into place), keeping the mode of an existing file
+    
+    -mkdir=false
+        Create the parent directories of -output, if missing
+    
+    -force=false
+        Regenerate every package, even if its inputs are unchanged since the last run
+        Writing to a file (-output, -split, or -site), godocdown keeps a cache
+        (one file per output directory, under -cache) of the hash of the inputs
+        of each package (source files, template, and options), skipping any
+        package that is unchanged, and reporting every file it writes
+    
+    -cache=""
+        The directory of the cache, by default godocdown in the user cache
+        directory (like ~/.cache/godocdown), rather than the output directory,
+        so that it doesn't end up committed with the documentation
+    
+    -no-examples=false
+        Do not render the examples of the tests of the package
+        The examples (func Example, ExampleF, ExampleT_M, ... of the _test.go files,
+        of the package itself or of its external test package, "package <name>_test")
+        are rendered after the documentation of what each is an example of, with
+        their output (if any)
+    
+    -package=""
+        Document the package of this name, of a directory having more than one
+        Otherwise (warning of the others) godocdown documents, in order of preference,
+        the package named after the directory, any other package (the one with the
+        most files), "package documentation" (the documentation of a command), or
+        "package main"
+    
+    -rev=""
+        Read the package as of a git revision (a commit, tag, or branch) of its
+        repository, without checking it out (with git ls-tree and git show):
+    
+            $ godocdown -rev v1.2.0 . > README.markdown
                                                                                      
     -template=""                                                                     
         The template file to use                                                     
@@ -54,6 +99,141 @@ Usage
             ([A-Za-z0-9_-]\s*)+                                                      
                                                                                      
         TitleCase1Word: The line matches either the TitleCase or 1Word pattern       
+    
+    -heading-offset=0
+        Shift every emitted heading down by this many levels, so that the
+        output can be nested under an existing heading (1 turns "#" into "##")
+    
+    -heading-style="atx"
+        Heading syntax: atx ("## Heading") or setext (an underlined heading)
+        Setext headings are only available for levels 1 and 2, deeper
+        levels always use atx
+    
+    -width=80
+        Wrap documentation text at this many columns
+    
+    -nowrap=false
+        Do not wrap documentation text, emit each paragraph as a single line
+        (Useful for keeping diffs small, or for renderers that wrap on their own)
+    
+    -keep-lines=false
+        Do not wrap documentation text, keep the line breaks of the original comment
+    
+    -no-escape=false
+        Do not escape Markdown characters (*, _, <T>, |, a leading #, ...) in
+        documentation text. Use this if your comments are deliberately written
+        in Markdown
+    
+    -markdown-comments=false
+        Treat documentation text as Markdown and pass it through as-is (no
+        wrapping, escaping, or heading detection), so that tables and lists
+        survive. A package can also opt in by including the directive
+        //godocdown:markdown in any of its files
+    
+    -fields=false
+        In addition to the declaration, render the fields of each struct type as
+        a table of name, type, tag (e.g. json:"name"), and description
+    
+    -implements=false
+        Type check the package, and list the interfaces each type implements (those
+        declared in the package, as well as error, fmt.Stringer, io.Reader, io.Writer,
+        io.Closer, and json.Marshaler), and the types that implement each interface
+    
+    -badges=false
+        Render badges after the title: pkg.go.dev and Go Report Card (from the
+        module path, of the nearest go.mod), the license (detected from a LICENSE
+        file of the module), and the minimum Go version (the go directive)
+    
+    -ci-badge=""
+        With -badges, the URL of a CI badge, with {import} (the import path),
+        {module} (the module path), and {repository} (the module path without
+        its host) replaced:
+    
+        -ci-badge 'https://github.com/{repository}/actions/workflows/go.yml/badge.svg'
+    
+    -ci-link=""
+        With -ci-badge, the URL the CI badge links to (by default, the badge itself)
+    
+    -install=false
+        Render an installation section after the synopsis, from the nearest go.mod:
+        go get <module>@latest for a package, go install <import path>@latest for
+        a command, and the minimum Go version (the go directive)
+    
+    -dependencies=false
+        Render the direct dependencies of the module (the requirements of its
+        go.mod that aren't "// indirect"), with their versions, at the end
+    
+    -split=""
+        Write the documentation into the given directory, as an index (README.md,
+        which also goes through the template) and one file per type (with its
+        constants, variables, constructors and methods), linked to each other
+        A page written before, of a type that is gone since, is removed (unless
+        it was changed since, or the package was documented at a -rev)
+    
+    -site=""
+        Document every package under the target directory (skipping hidden and
+        _ignored directories, testdata, and vendor) into the given directory:
+        one page per package, linked to its parent, subpackages, and the packages
+        it imports or is imported by, with an index of every package. A
+        qualified identifier (like c.C) in documentation text links to the
+        page of its package, if the package imports it
+    
+    -site-format=""
+        Adapt the pages of -site for a static site generator:
+        hugo: YAML front matter (title, weight, description), and _index.md pages
+        docusaurus: YAML front matter (title, sidebar_position, description)
+        mkdocs: A nav section in mkdocs.nav.yml (INHERIT it from mkdocs.yml)
+    
+    -format="markdown"
+        The output format: markdown, asciidoc (for Asciidoctor), rst
+        (reStructuredText, for Sphinx), or man for a man page (roff) of a command:
+        NAME and DESCRIPTION from the package documentation (with headings as
+        subsections), SYNOPSIS from "$ command ..." examples, and OPTIONS from
+        the flags the command defines. With -split or -site, pages are named
+        .adoc or .rst to match (docusaurus and mkdocs only support markdown)
+    
+    -lint=false
+        Instead of documentation, report the exported symbols that are missing a
+        doc comment (or whose comment doesn't start with their name), undocumented
+        struct fields, and a missing package comment, with the documentation
+        coverage of the package. Check every package under path with "path/..."
+    
+    -lint-threshold=0
+        With -lint, exit non-zero if the coverage of a package is below this percentage
+    
+    -diff=""
+        Instead of documentation, report the changes to the API of the package
+        from OLD to NEW, given as OLD,NEW (see API Changes)
+
+Code Blocks
+
+Unless "plain" is given, preformatted (indented) blocks in documentation are emitted as fenced code
+blocks. godocdown guesses the language of each block: shell for a leading "$ " prompt, JSON if
+the block decodes as JSON, and Go if the block parses as Go source. To choose the language
+explicitly, start the block with a hint line:
+
+    // Example configuration:
+    //
+    //     ```yaml
+    //     name: example
+
+API Changes
+
+Given -diff, godocdown compares the exported constants, variables, functions, types, methods and
+(struct) fields of two versions of a package, and emits a report of what was added, removed, and
+changed (with a diff of each changed declaration), e.g. for the release notes:
+
+    # Compare two git revisions of the package in the current directory
+    $ godocdown -diff v1.0.0,HEAD
+    
+    # Compare two git revisions of another package
+    $ godocdown -diff v1.0.0,HEAD ./path/to/package
+    
+    # Compare two directories
+    $ godocdown -diff /path/to/old,/path/to/new
+
+A side is a directory if there is one by that name, otherwise it is a git revision. A constant
+declared with iota is compared by the value of iota too, so that a constant that moves is changed.
 
 Templating
 
@@ -76,6 +256,14 @@ Along with the standard template functionality, the starting data argument has t
                                                                                                       
     {{ .EmitHeader }}                                                                                 
     // Emit the package name and an import line (if one is present/needed)                            
+    
+    {{ .EmitBadges }}
+    // Emit the badges (see -badges), wherever the template would have them
+    // (then, the header leaves them out, so that they are only emitted once)
+    
+    {{ .EmitInstall }}
+    {{ .EmitDependencies }}
+    // Emit the installation section, or the dependencies (see -install and -dependencies)
                                                                                                       
     {{ .EmitSynopsis }}                                                                               
     // Emit the package declaration                                                                   
@@ -94,6 +282,20 @@ Along with the standard template functionality, the starting data argument has t
     {{ .ImportPath }}                                                                                 
     // The import path for the package (string)                                                       
     // (This field will be the empty string if godocdown is unable to guess it)                       
+    
+    {{ if .MarkdownComments }} ... {{ end }}
+    // A boolean indicating whether the package documentation is written in Markdown
+
+In addition, the following functions are available within a template:
+
+    {{ headingOffset 1 }}
+    // Shift every heading emitted after this point (in this document) down by the given number of levels
+    
+    {{ heading 2 "Example" }}
+    // Emit a heading of the given level (honoring -heading-offset and -heading-style)
+    
+    {{ format "asciidoc" }}
+    // Emit everything after this point (in this document) as markdown, asciidoc, or rst
 */
 package main
 
@@ -101,15 +303,19 @@ import (
 	"bytes"
 	Flag "flag"
 	"fmt"
+	"go/ast"
 	"go/build"
 	"go/doc"
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	Template "text/template"
 	Time "time"
@@ -121,16 +327,43 @@ const (
 )
 
 var (
-	flag            = Flag.NewFlagSet("", Flag.ExitOnError)
-	flag_signature  = flag.Bool("signature", false, string(0))
-	flag_plain      = flag.Bool("plain", false, "Emit standard Markdown, rather than Github Flavored Markdown (the default)")
-	flag_heading    = flag.String("heading", "TitleCase1Word", "Heading detection method: 1Word, TitleCase, Title, TitleCase1Word, \"\"")
-	flag_template   = flag.String("template", "", "The template file to use")
-	flag_noTemplate = flag.Bool("no-template", false, "Disable template processing")
-	flag_output     = ""
-	_               = func() byte {
+	flag               = Flag.NewFlagSet("", Flag.ExitOnError)
+	flag_signature     = flag.Bool("signature", false, "\x00")
+	flag_plain         = flag.Bool("plain", false, "Emit standard Markdown, rather than Github Flavored Markdown (the default)")
+	flag_heading       = flag.String("heading", "TitleCase1Word", "Heading detection method: 1Word, TitleCase, Title, TitleCase1Word, \"\"")
+	flag_template      = flag.String("template", "", "The template file to use")
+	flag_noTemplate    = flag.Bool("no-template", false, "Disable template processing")
+	flag_headingOffset = flag.Int("heading-offset", 0, "Shift every emitted heading down by this many levels")
+	flag_headingStyle  = flag.String("heading-style", "atx", "Heading syntax: atx (\"# Heading\") or setext (underlined, for levels 1 and 2)")
+	flag_width         = flag.Int("width", punchCardWidth, "Wrap documentation text at this many columns")
+	flag_noWrap        = flag.Bool("nowrap", false, "Do not wrap documentation text, emit each paragraph as a single line")
+	flag_keepLines     = flag.Bool("keep-lines", false, "Do not wrap documentation text, keep the line breaks of the original comment")
+	flag_noEscape      = flag.Bool("no-escape", false, "Do not escape Markdown characters in documentation text")
+	flag_markdown      = flag.Bool("markdown-comments", false, "Treat documentation text as Markdown, passing it through as-is")
+	flag_fields        = flag.Bool("fields", false, "Render the fields of each struct type as a table (name, type, tag, description)")
+	flag_split         = flag.String("split", "", "Write an index, and one file per type, into the given directory")
+	flag_site          = flag.String("site", "", "Document every package under the target directory, writing a page per package and an index into the given directory")
+	flag_siteFormat    = flag.String("site-format", "", "Adapt -site for a static site generator: hugo, docusaurus, or mkdocs")
+	flag_format        = flag.String("format", "markdown", "Output format: markdown, asciidoc, rst (reStructuredText), or man (a man page, for commands)")
+	flag_implements    = flag.Bool("implements", false, "List the interfaces each type implements, and the types implementing each interface")
+	flag_noExamples    = flag.Bool("no-examples", false, "Do not render the examples of the tests of the package")
+	flag_badges        = flag.Bool("badges", false, "Render badges after the title: pkg.go.dev, Go Report Card, license, and Go version")
+	flag_ciBadge       = flag.String("ci-badge", "", "With -badges, the URL of a CI badge, with {import}, {module}, and {repository} replaced")
+	flag_ciLink        = flag.String("ci-link", "", "With -ci-badge, the URL the CI badge links to (by default, the badge itself)")
+	flag_install       = flag.Bool("install", false, "Render an installation section (go get, or go install for a command) from the go.mod of the module")
+	flag_dependencies  = flag.Bool("dependencies", false, "Render the direct dependencies of the module (from its go.mod)")
+	flag_lint          = flag.Bool("lint", false, "Report exported symbols that are missing documentation (or whose comment doesn't start with their name), and the documentation coverage")
+	flag_lintThreshold = flag.Float64("lint-threshold", 0, "With -lint, exit non-zero if the documentation coverage of a package is below this percentage")
+	flag_diff          = flag.String("diff", "", "Report the changes to the API of the package from OLD to NEW (git revisions or directories), given as OLD,NEW")
+	flag_mkdir         = flag.Bool("mkdir", false, "Create the parent directories of -output, if missing")
+	flag_force         = flag.Bool("force", false, "Regenerate every package, even if its inputs are unchanged since the last run")
+	flag_cache         = flag.String("cache", "", "The directory of the cache (see -force), by default godocdown in the user cache directory")
+	flag_package       = flag.String("package", "", "Document the package of this name, of a directory having more than one")
+	flag_rev           = flag.String("rev", "", "Read the package as of a git revision (a commit, tag, or branch) of its repository, without checking it out")
+	flag_output        = ""
+	_                  = func() byte {
 		flag.StringVar(&flag_output, "output", flag_output, "Write output to a file instead of stdout. Write to stdout with -")
-		flag.StringVar(&flag_output, "o", flag_output, string(0))
+		flag.StringVar(&flag_output, "o", flag_output, "\x00")
 		return 0
 	}()
 )
@@ -152,16 +385,45 @@ var (
 var DefaultStyle = Style{
 	IncludeImport: true,
 
-	SynopsisHeader:  "###",
+	Format: "markdown",
+
+	TitleHeader: 1,
+
+	SynopsisHeader:  3,
 	SynopsisHeading: synopsisHeadingTitleCase1Word_Regexp,
 
-	UsageHeader: "## Usage\n",
+	UsageHeader: 2,
+
+	ConstantHeader:     4,
+	VariableHeader:     4,
+	FunctionHeader:     4,
+	TypeHeader:         4,
+	TypeFunctionHeader: 4,
+	ConstraintHeader:   3,
+
+	HeadingOffset: 0,
+	HeadingSetext: false,
+
+	Width:     punchCardWidth,
+	NoWrap:    false,
+	KeepLines: false,
+
+	EscapeMarkdown: true,
+
+	MarkdownComments: false,
 
-	ConstantHeader:     "####",
-	VariableHeader:     "####",
-	FunctionHeader:     "####",
-	TypeHeader:         "####",
-	TypeFunctionHeader: "####",
+	IncludeFields:     false,
+	IncludeImplements: false,
+	IncludeExamples:   true,
+
+	IncludeBadges: false,
+	CIBadge:       "",
+	CILink:        "",
+
+	IncludeInstall:      false,
+	IncludeDependencies: false,
+
+	SiteFormat: "",
 
 	IncludeSignature: false,
 }
@@ -183,38 +445,135 @@ func init() {
 	flag.Usage = usage
 }
 
+// Style controls the shape of the emitted Markdown. Each *Header field
+// is a heading level (1 for "#", 2 for "##", ...), before HeadingOffset is applied.
 type Style struct {
 	IncludeImport bool
 
-	SynopsisHeader  string
-	SynopsisHeading *regexp.Regexp
+	// Format is the markup of the output (see rendererMap): markdown,
+	// plain (standard Markdown), asciidoc, or rst
+	Format string
 
-	UsageHeader string
+	TitleHeader int
 
-	ConstantHeader     string
-	VariableHeader     string
-	FunctionHeader     string
-	TypeHeader         string
-	TypeFunctionHeader string
+	SynopsisHeader  int
+	SynopsisHeading *regexp.Regexp
+
+	UsageHeader int
+
+	ConstantHeader     int
+	VariableHeader     int
+	FunctionHeader     int
+	TypeHeader         int
+	TypeFunctionHeader int
+
+	// ConstraintHeader is the level of the heading for the section of
+	// interfaces that are used as type parameter constraints
+	ConstraintHeader int
+
+	// HeadingOffset is added to every heading level, so that the output
+	// can be nested under an existing heading in a larger document.
+	HeadingOffset int
+
+	// HeadingSetext uses underlined (Setext) headings for levels 1 and 2.
+	// Deeper levels always use ATX ("###") headings.
+	HeadingSetext bool
+
+	// Width is the column at which documentation text is wrapped.
+	// NoWrap emits each paragraph as a single line instead, and KeepLines
+	// keeps the line breaks of the original comment.
+	Width     int
+	NoWrap    bool
+	KeepLines bool
+
+	// EscapeMarkdown escapes characters in documentation text that
+	// Markdown would otherwise treat as markup (*, _, <T>, |, a leading #, ...),
+	// or AsciiDoc or reStructuredText, depending on Format
+	EscapeMarkdown bool
+
+	// MarkdownComments passes documentation text through as Markdown:
+	// no wrapping, no escaping, and no heading detection
+	MarkdownComments bool
+
+	// IncludeFields renders the fields of each struct type as a table,
+	// in addition to the declaration
+	IncludeFields bool
+
+	// IncludeImplements type checks the package to list the interfaces
+	// each type implements (and the types that implement each interface)
+	IncludeImplements bool
+
+	// IncludeExamples renders the examples of the tests of the package
+	// (of the package itself, and of its external test package) after
+	// the documentation of what each is an example of
+	IncludeExamples bool
+
+	// IncludeBadges renders a row of badges after the title (pkg.go.dev,
+	// Go Report Card, license, Go version), with a CI badge if CIBadge
+	// (a URL pattern, see expandBadgePattern) is not "", linking to CILink
+	// (or the badge itself, if "")
+	IncludeBadges bool
+	CIBadge       string
+	CILink        string
+
+	// IncludeInstall renders an installation section after the synopsis,
+	// and IncludeDependencies the direct dependencies of the module at
+	// the end (both from the go.mod of the module, see findModule)
+	IncludeInstall      bool
+	IncludeDependencies bool
+
+	// SiteFormat adapts the pages of -site for a static site generator
+	// (hugo, docusaurus, or mkdocs), or "" for plain Markdown files
+	SiteFormat string
+
+	// SiteLinks maps the name of each package of the site that the package
+	// being rendered imports to a link to its page, so that a qualified
+	// identifier (like c.C) in documentation text links there (see -site)
+	SiteLinks map[string]string
 
 	IncludeSignature bool
 }
 
 type _document struct {
-	Name       string
-	pkg        *doc.Package
-	buildPkg   *build.Package
-	IsCommand  bool
-	ImportPath string
+	Name             string
+	pkg              *doc.Package
+	buildPkg         *build.Package
+	IsCommand        bool
+	ImportPath       string
+	MarkdownComments bool
+	implements       *_implements
+	flags            []_flag
+	constants        map[string]string // The declaration of each constant (see findConstants)
+	split            bool
+	badgesPlaced     bool   // By the template (see EmitBadges), rather than after the title
+	outputDirectory  string // Where the documentation is written, or "" for stdout (see fileLink)
+
+	// The filesystem (and directory within it) the package was read from
+	fsys      fs.FS
+	directory string
 }
 
 func takeOut7f(input string) string {
 	return match_7f.ReplaceAllString(input, "")
 }
 
-func _formatIndent(target, indent, preIndent string) string {
+func wrapWidth(indent string) int {
+	switch {
+	case RenderStyle.KeepLines:
+		return wrapLines
+	case RenderStyle.NoWrap, RenderStyle.Width <= 0:
+		return wrapNone
+	}
+	width := RenderStyle.Width - 2*len(indent)
+	if width < 1 {
+		width = 1
+	}
+	return width
+}
+
+func _formatIndent(target, indent string) string {
 	var buffer bytes.Buffer
-	toText(&buffer, target, indent, preIndent, punchCardWidth-2*len(indent))
+	toText(&buffer, target, indent, wrapWidth(indent))
 	return buffer.String()
 }
 
@@ -223,26 +582,89 @@ func spacer(width int) string {
 }
 
 func formatIndent(target string) string {
-	return _formatIndent(target, spacer(0), spacer(4))
+	if RenderStyle.MarkdownComments {
+		return formatMarkdown(target)
+	}
+	return _formatIndent(target, spacer(0))
+}
+
+// formatMarkdown passes documentation text through as Markdown, only
+// removing the indentation common to every line.
+func formatMarkdown(target string) string {
+	lines := strings.SplitAfter(strings.TrimRight(target, " \t\n"), "\n")
+	unindent(lines)
+	target = strings.Join(lines, "")
+	if target == "" {
+		return ""
+	}
+	return target + "\n"
 }
 
 func indentCode(target string) string {
-	if *flag_plain {
-		return indent(target+"\n", spacer(4))
+	return renderer().CodeBlock("go", target)
+}
+
+// formatHeading returns text as a heading of the given level (in the
+// output format), shifted by RenderStyle.HeadingOffset.
+func formatHeading(level int, text string) string {
+	level += RenderStyle.HeadingOffset
+	if level < 1 {
+		level = 1
 	}
-	return fmt.Sprintf("```go\n%s\n```", target)
+	if level > 6 {
+		level = 6
+	}
+	return renderer().Heading(level, text)
 }
 
 func headifySynopsis(target string) string {
 	detect := RenderStyle.SynopsisHeading
-	if detect == nil {
+	if detect == nil || RenderStyle.MarkdownComments {
 		return target
 	}
-	return detect.ReplaceAllStringFunc(target, func(heading string) string {
-		return fmt.Sprintf("%s %s", RenderStyle.SynopsisHeader, heading)
+	return outsideFence(target, func(target string) string {
+		if !RenderStyle.KeepLines {
+			return detect.ReplaceAllStringFunc(target, func(heading string) string {
+				return formatHeading(RenderStyle.SynopsisHeader, heading)
+			})
+		}
+		// A kept line is only a heading if it is a paragraph of its own, not
+		// just any short line in the middle of one
+		var buffer bytes.Buffer
+		last := 0
+		for _, match := range detect.FindAllStringIndex(target, -1) {
+			if !isParagraph(target, match[0], match[1]) {
+				continue
+			}
+			buffer.WriteString(target[last:match[0]])
+			buffer.WriteString(formatHeading(RenderStyle.SynopsisHeader, target[match[0]:match[1]]))
+			last = match[1]
+		}
+		buffer.WriteString(target[last:])
+		return buffer.String()
 	})
 }
 
+// isParagraph reports whether the line of text from start to end is a
+// paragraph of its own: blank (or nothing) before and after it.
+func isParagraph(text string, start, end int) bool {
+	before := strings.TrimRight(text[:start], " \t")
+	if before != "" {
+		before = strings.TrimRight(strings.TrimSuffix(before, "\n"), " \t")
+		if before != "" && !strings.HasSuffix(before, "\n") {
+			return false
+		}
+	}
+	after := strings.TrimLeft(text[end:], " \t")
+	if after != "" {
+		after = strings.TrimLeft(strings.TrimPrefix(after, "\n"), " \t")
+		if after != "" && !strings.HasPrefix(after, "\n") {
+			return false
+		}
+	}
+	return true
+}
+
 func headlineSynopsis(synopsis, header string, scanner *regexp.Regexp) string {
 	return scanner.ReplaceAllStringFunc(synopsis, func(headline string) string {
 		return fmt.Sprintf("%s %s", header, headline)
@@ -344,9 +766,58 @@ func guessImportPath(target string) (string, error) {
 	return buildPkg.ImportPath, nil
 }
 
+// isSourceFile reports whether target is a single .go file (rather than a package)
+func isSourceFile(target string) bool {
+	if !strings.HasSuffix(target, ".go") {
+		return false
+	}
+	info, err := os.Stat(target)
+	return err == nil && !info.IsDir()
+}
+
+// parseFile parses a single file (from source, if not nil) as a package of its own
+func parseFile(filename string, source []byte) (map[string]*ast.Package, error) {
+	var src interface{}
+	if source != nil {
+		src = source
+	}
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*ast.Package{
+		file.Name.Name: &ast.Package{
+			Name:  file.Name.Name,
+			Files: map[string]*ast.File{filename: file},
+		},
+	}, nil
+}
+
+// loadDocument loads the package in the target directory, or a single .go
+// file, or a package in a zip (see loadArchive), or (with a target of "-")
+// source read from stdin. A file (or stdin)
+// is documented as if it were the only file of the package in its directory
+// (the current directory, for stdin).
 func loadDocument(target string) (*_document, error) {
 
-	buildPkg, err := buildImport(target)
+	if archive, directory, ok := splitArchive(target); ok {
+		return loadArchive(archive, directory)
+	}
+
+	directory := target
+	filename := ""
+	var source []byte
+	if target == "-" {
+		read, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read stdin: %v", err)
+		}
+		directory, filename, source = ".", "<stdin>", read
+	} else if isSourceFile(target) {
+		directory, filename = filepath.Dir(target), target
+	}
+
+	buildPkg, err := buildImport(directory)
 	if err != nil {
 		return nil, err
 	}
@@ -354,74 +825,190 @@ func loadDocument(target string) (*_document, error) {
 		return nil, fmt.Errorf("Could not find package \"%s\"", target)
 	}
 
-	path := buildPkg.Dir
+	if filename == "" {
+		return loadDocumentFS(os.DirFS(buildPkg.Dir), ".", buildPkg)
+	}
 
 	fset = token.NewFileSet()
-	pkgSet, err := parser.ParseDir(fset, path, func(file os.FileInfo) bool {
-		name := file.Name()
-		if name[0] != '.' && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
-			return true
-		}
-		return false
-	}, parser.ParseComments)
+	pkgSet, err := parseFile(filename, source)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse \"%s\": %v", filename, err)
+	}
+	document, err := documentOf(pkgSet, nil, os.DirFS(buildPkg.Dir), ".", buildPkg)
+	if document != nil {
+		// A single file (or source from stdin, in the current directory)
+		// outside of GOPATH has the import path of its module, if any, not
+		// its directory
+		document.ImportPath = resolveImportPath(document, findModule(document))
+	}
+	return document, err
+}
+
+// loadDocumentFS loads the package in directory of fsys, which need not be
+// the disk (a module zip, say), reading .godocdown.import and the template
+// from there too. The files of the package are named (in positions, and
+// errors) after buildPkg.Dir.
+func loadDocumentFS(fsys fs.FS, directory string, buildPkg *build.Package) (*_document, error) {
+	fset = token.NewFileSet()
+	pkgSet, err := parseDir(fsys, directory, buildPkg.Dir, false)
 	if err != nil {
-		return nil, fmt.Errorf("Could not parse \"%s\": %v", path, err)
+		return nil, fmt.Errorf("Could not parse \"%s\": %v", buildPkg.Dir, err)
+	}
+	var testSet map[string]*ast.Package
+	if RenderStyle.IncludeExamples {
+		// The tests are only for their examples, one that doesn't parse is no matter
+		testSet, _ = parseDir(fsys, directory, buildPkg.Dir, true)
+	}
+	return documentOf(pkgSet, testSet, fsys, directory, buildPkg)
+}
+
+// documentOf chooses the package to document of those parsed from directory
+// of fsys, with the examples of its tests (of testSet, which has both the
+// package and its external test package, "package <name>_test")
+func documentOf(pkgSet, testSet map[string]*ast.Package, fsys fs.FS, directory string, buildPkg *build.Package) (*_document, error) {
+	path := buildPkg.Dir
+
+	markdownComments := false
+	for _, parsePkg := range pkgSet {
+		if hasMarkdownDirective(parsePkg) {
+			markdownComments = true
+		}
 	}
 
 	importPath := ""
-	if read, err := ioutil.ReadFile(filepath.Join(path, ".godocdown.import")); err == nil {
+	if read, err := readFS(fsys, directory, ".godocdown.import"); err == nil {
 		importPath = strings.TrimSpace(strings.Split(string(read), "\n")[0])
 	} else {
 		importPath = buildPkg.ImportPath
 	}
 
-	{
-		isCommand := false
-		name := ""
-		var pkg *doc.Package
-
-		// Choose the best package for documentation. Either
-		// documentation, main, or whatever the package is.
-		for _, parsePkg := range pkgSet {
-			tmpPkg := doc.New(parsePkg, ".", 0)
-			switch tmpPkg.Name {
-			case "main":
-				if isCommand || name != "" {
-					// We've already seen "package documentation"
-					// (or something else), so favor that over main.
-					continue
-				}
-				fallthrough
-			case "documentation":
-				// We're a command, this package/file contains the documentation
-				// path is used to get the containing directory in the case of
-				// command documentation
-				path, err := filepath.Abs(path)
-				if err != nil {
-					panic(err)
-				}
-				_, name = filepath.Split(path)
-				isCommand = true
-				pkg = tmpPkg
-			default:
-				// Just a regular package
-				name = tmpPkg.Name
-				pkg = tmpPkg
-			}
+	parsePkg, err := choosePackage(pkgSet, path)
+	if parsePkg == nil {
+		return nil, err
+	}
+
+	var implements *_implements
+	if RenderStyle.IncludeImplements {
+		// Before doc.New, which trims the AST
+		implements = checkImplements(fset, parsePkg)
+	}
+	flags := findFlags(fset, parsePkg)
+	constants := findConstants(parsePkg)
+	pkg := doc.New(parsePkg, ".", 0)
+	classifyExamples(pkg, findExamples(testSet, parsePkg.Name))
+
+	isCommand := false
+	name := pkg.Name
+	if name == "main" || name == "documentation" {
+		// We're a command, this package/file contains the documentation
+		// path is used to get the containing directory in the case of
+		// command documentation
+		path, err := filepath.Abs(path)
+		if err != nil {
+			panic(err)
 		}
+		_, name = filepath.Split(path)
+		isCommand = true
+	}
+
+	return &_document{
+		Name:             name,
+		pkg:              pkg,
+		buildPkg:         buildPkg,
+		IsCommand:        isCommand,
+		ImportPath:       importPath,
+		MarkdownComments: markdownComments,
+		implements:       implements,
+		flags:            flags,
+		constants:        constants,
+		fsys:             fsys,
+		directory:        directory,
+	}, nil
+}
+
+// A package of a directory, ranked for choosePackage
+type _packageChoice struct {
+	pkg  *ast.Package
+	rank int
+}
+
+type _packageChoiceSort []_packageChoice
 
-		if pkg != nil {
-			return &_document{
-				Name:       name,
-				pkg:        pkg,
-				buildPkg:   buildPkg,
-				IsCommand:  isCommand,
-				ImportPath: importPath,
-			}, nil
+func (self _packageChoiceSort) Len() int      { return len(self) }
+func (self _packageChoiceSort) Swap(i, j int) { self[i], self[j] = self[j], self[i] }
+func (self _packageChoiceSort) Less(i, j int) bool {
+	if self[i].rank != self[j].rank {
+		return self[i].rank < self[j].rank
+	}
+	if len(self[i].pkg.Files) != len(self[j].pkg.Files) {
+		return len(self[i].pkg.Files) > len(self[j].pkg.Files)
+	}
+	return self[i].pkg.Name < self[j].pkg.Name
+}
+
+// choosePackage chooses the package to document, of those parsed from the
+// directory path: the one named by -package or else, in order of preference,
+// the package named after the directory, any other package (the one with the
+// most files, then the first by name), "package documentation" (the
+// documentation of a command), and "package main". Choosing among several
+// packages, it warns of the others.
+func choosePackage(pkgSet map[string]*ast.Package, path string) (*ast.Package, error) {
+	if *flag_package != "" {
+		if strings.HasSuffix(*flag_package, "_test") {
+			return nil, fmt.Errorf("Could not document package \"%s\" in \"%s\", an external test package is only ever for its examples", *flag_package, path)
+		}
+		if pkg, exists := pkgSet[*flag_package]; exists {
+			return pkg, nil
+		}
+		return nil, fmt.Errorf("Could not find package \"%s\" in \"%s\"", *flag_package, path)
+	}
+
+	base := filepath.Base(path)
+	var choiceList []_packageChoice
+	for _, pkg := range pkgSet {
+		if strings.HasSuffix(pkg.Name, "_test") {
+			continue // An external test package is only ever for its examples
+		}
+		rank := 1
+		switch pkg.Name {
+		case base:
+			rank = 0
+		case "documentation":
+			rank = 2
+		case "main":
+			rank = 3
 		}
+		choiceList = append(choiceList, _packageChoice{pkg, rank})
 	}
+	if len(choiceList) == 0 {
+		return nil, nil
+	}
+	sort.Sort(_packageChoiceSort(choiceList))
 
-	return nil, nil
+	if len(choiceList) > 1 {
+		var nameList []string
+		for _, choice := range choiceList[1:] {
+			nameList = append(nameList, choice.pkg.Name)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: \"%s\" has more than one package, documenting %s rather than %s (choose with -package)\n",
+			path, choiceList[0].pkg.Name, strings.Join(nameList, ", "))
+	}
+	return choiceList[0].pkg, nil
+}
+
+// hasMarkdownDirective reports whether a file of the package contains
+// a "//godocdown:markdown" directive (which go/doc leaves out of the documentation).
+func hasMarkdownDirective(pkg *ast.Package) bool {
+	for _, file := range pkg.Files {
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if strings.TrimSpace(comment.Text) == "//godocdown:markdown" {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
 
 func emitString(fn func(*bytes.Buffer)) string {
@@ -446,11 +1033,21 @@ func (self *_document) EmitTo(buffer *bytes.Buffer) {
 	// Synopsis
 	self.EmitSynopsisTo(buffer)
 
+	// Installation
+	if RenderStyle.IncludeInstall {
+		self.EmitInstallTo(buffer)
+	}
+
 	// Usage
 	if !self.IsCommand {
 		self.EmitUsageTo(buffer)
 	}
 
+	// Dependencies
+	if RenderStyle.IncludeDependencies {
+		self.EmitDependenciesTo(buffer)
+	}
+
 	trimSpace(buffer)
 }
 
@@ -479,6 +1076,40 @@ func (self *_document) EmitHeaderTo(buffer *bytes.Buffer) {
 	renderHeaderTo(buffer, self)
 }
 
+// EmitBadges returns the badges (see -badges), for a template
+// that places them itself
+func (self *_document) EmitBadges() string {
+	return emitString(func(buffer *bytes.Buffer) {
+		self.EmitBadgesTo(buffer)
+	})
+}
+
+func (self *_document) EmitBadgesTo(buffer *bytes.Buffer) {
+	renderBadgesTo(buffer, self)
+}
+
+// EmitInstall returns the installation section (see -install)
+func (self *_document) EmitInstall() string {
+	return emitString(func(buffer *bytes.Buffer) {
+		self.EmitInstallTo(buffer)
+	})
+}
+
+func (self *_document) EmitInstallTo(buffer *bytes.Buffer) {
+	renderInstallTo(buffer, self)
+}
+
+// EmitDependencies returns the list of direct dependencies (see -dependencies)
+func (self *_document) EmitDependencies() string {
+	return emitString(func(buffer *bytes.Buffer) {
+		self.EmitDependenciesTo(buffer)
+	})
+}
+
+func (self *_document) EmitDependenciesTo(buffer *bytes.Buffer) {
+	renderDependenciesTo(buffer, self)
+}
+
 // Synopsis
 func (self *_document) EmitSynopsis() string {
 	return emitString(func(buffer *bytes.Buffer) {
@@ -524,22 +1155,53 @@ func findTemplate(path string) string {
 	return "" // Nothing found
 }
 
-func loadTemplate(document *_document) *Template.Template {
+// templatePathOf returns the template for the package in directory
+// (-template, or one found in directory), or "" for none
+func templatePathOf(directory string) string {
 	if *flag_noTemplate {
-		return nil
+		return ""
 	}
-
-	templatePath := *flag_template
-	if templatePath == "" {
-		templatePath = findTemplate(document.buildPkg.Dir)
+	if *flag_template != "" {
+		return *flag_template
 	}
+	return findTemplate(directory)
+}
 
+func loadTemplate(document *_document) *Template.Template {
+	templatePath := templatePathOf(document.buildPkg.Dir)
+	var fsys fs.FS
+	if document.fsys != nil && *flag_template == "" && !*flag_noTemplate {
+		// Found alongside the package, which need not be on disk
+		fsys = document.fsys
+		templatePath = findTemplateFS(fsys, document.directory)
+	}
 	if templatePath == "" {
 		return nil
 	}
 
-	template := Template.New("").Funcs(Template.FuncMap{})
-	template, err := template.ParseFiles(templatePath)
+	template := Template.New("").Funcs(Template.FuncMap{
+		"heading": func(level int, text string) string {
+			return formatHeading(level, text)
+		},
+		"headingOffset": func(offset int) string {
+			RenderStyle.HeadingOffset = offset
+			return ""
+		},
+		"format": func(format string) (string, error) {
+			if format == "man" || !validFormat(format) {
+				return "", fmt.Errorf("unknown format: %s", format)
+			}
+			selectFormat(format)
+			return "", nil
+		},
+	})
+	var err error
+	if fsys != nil {
+		template, err = template.ParseFS(fsys, pathpkg.Join(document.directory, templatePath))
+		templatePath = filepath.Join(document.buildPkg.Dir, templatePath)
+	} else {
+		template, err = template.ParseFiles(templatePath)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing template \"%s\": %v", templatePath, err)
 		os.Exit(1)
@@ -547,6 +1209,42 @@ func loadTemplate(document *_document) *Template.Template {
 	return template
 }
 
+// keepStyle saves RenderStyle, returning a function that restores it
+func keepStyle() func() {
+	style := RenderStyle
+	return func() {
+		RenderStyle = style
+	}
+}
+
+// emitDocument renders the documentation, through the template (if any).
+// Whatever the template changes of the style (with headingOffset, say) is
+// for this document only.
+func emitDocument(document *_document, template *Template.Template) (string, error) {
+	defer keepStyle()()
+	var buffer bytes.Buffer
+	resetHeadings()
+	if *flag_format == "man" {
+		renderManTo(&buffer, document)
+		return strings.TrimSpace(buffer.String()), nil
+	}
+	if template == nil {
+		document.EmitTo(&buffer)
+		document.EmitSignatureTo(&buffer)
+	} else {
+		document.badgesPlaced = placesBadges(template.Templates()[0].Tree.Root)
+		defer func() {
+			document.badgesPlaced = false
+		}()
+		err := template.Templates()[0].Execute(&buffer, document)
+		if err != nil {
+			return "", err
+		}
+		document.EmitSignatureTo(&buffer)
+	}
+	return strings.TrimSpace(buffer.String()), nil
+}
+
 func main() {
 	flag.Parse(os.Args[1:])
 	target := flag.Arg(0)
@@ -557,6 +1255,51 @@ func main() {
 	}
 
 	RenderStyle.IncludeSignature = *flag_signature
+	RenderStyle.HeadingOffset = *flag_headingOffset
+	RenderStyle.Width = *flag_width
+	RenderStyle.NoWrap = *flag_noWrap
+	RenderStyle.KeepLines = *flag_keepLines
+	RenderStyle.EscapeMarkdown = !*flag_noEscape
+	RenderStyle.MarkdownComments = *flag_markdown
+	RenderStyle.IncludeFields = *flag_fields
+	RenderStyle.IncludeImplements = *flag_implements
+	RenderStyle.IncludeExamples = !*flag_noExamples
+	RenderStyle.IncludeBadges = *flag_badges
+	RenderStyle.CIBadge = *flag_ciBadge
+	RenderStyle.CILink = *flag_ciLink
+	RenderStyle.IncludeInstall = *flag_install
+	RenderStyle.IncludeDependencies = *flag_dependencies
+
+	if !validFormat(*flag_format) {
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", *flag_format)
+		os.Exit(2)
+	}
+	if *flag_format != "man" {
+		selectFormat(*flag_format)
+	} else if *flag_split != "" || *flag_site != "" {
+		fmt.Fprintf(os.Stderr, "A man page can't be written with -split or -site\n")
+		os.Exit(2)
+	}
+
+	RenderStyle.SiteFormat = *flag_siteFormat
+	if !validSiteFormat(RenderStyle.SiteFormat) {
+		fmt.Fprintf(os.Stderr, "Unknown site format: %s\n", RenderStyle.SiteFormat)
+		os.Exit(2)
+	}
+	if !siteFormatSupports(RenderStyle.SiteFormat, RenderStyle.Format) {
+		fmt.Fprintf(os.Stderr, "The %s site format only supports Markdown\n", RenderStyle.SiteFormat)
+		os.Exit(2)
+	}
+
+	switch *flag_headingStyle {
+	case "atx", "":
+		RenderStyle.HeadingSetext = false
+	case "setext":
+		RenderStyle.HeadingSetext = true
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown heading style: %s\n", *flag_headingStyle)
+		os.Exit(2)
+	}
 
 	switch *flag_heading {
 	case "1Word":
@@ -571,7 +1314,71 @@ func main() {
 		RenderStyle.SynopsisHeading = nil
 	}
 
-	document, err := loadDocument(target)
+	if *flag_lint {
+		report, pass, err := emitLint(target, *flag_lintThreshold)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		writeDocumentation(report, nil)
+		if !pass {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *flag_diff != "" {
+		sideList := strings.SplitN(*flag_diff, ",", 2)
+		if len(sideList) < 2 || sideList[0] == "" || sideList[1] == "" {
+			fmt.Fprintf(os.Stderr, "Usage: %s -diff OLD,NEW [PACKAGE]\n", os.Args[0])
+			os.Exit(2)
+		}
+		documentation, err := emitDiff(sideList[0], sideList[1], target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		writeDocumentation(documentation, nil)
+		return
+	}
+
+	if *flag_site != "" {
+		err := emitSite(target, *flag_site)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Writing to a file (or -split), skip the package if it is unchanged
+	var cache *_cache
+	var entry *_cacheEntry
+	directory, extra := target, ""
+	if isSourceFile(target) {
+		directory, extra = filepath.Dir(target), filepath.Base(target)
+	}
+	if buildPkg, err := buildImport(directory); err == nil && buildPkg.Dir != "" && target != "-" && *flag_rev == "" && !debug {
+		if *flag_split != "" {
+			cache = loadCache(*flag_split)
+		} else if flag_output != "" && flag_output != "-" {
+			cache = loadCache(filepath.Dir(flag_output))
+		}
+		fresh := false
+		entry, fresh = cache.lookup(buildPkg.Dir, extra)
+		if fresh {
+			fmt.Fprintf(os.Stderr, "%s is up to date\n", target)
+			return
+		}
+	}
+
+	var document *_document
+	var err error
+	if *flag_rev != "" {
+		document, err = loadRevision(target, *flag_rev)
+	} else {
+		document, err = loadDocument(target)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
 	}
@@ -581,40 +1388,72 @@ func main() {
 			usage()
 			os.Exit(2)
 		} else {
-			fmt.Fprintf(os.Stderr, "Could not find package: %s\n", target)
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "Could not find package: %s\n", target)
+			}
 			os.Exit(1)
 		}
 	}
 
+	if document.MarkdownComments {
+		RenderStyle.MarkdownComments = true
+	}
+
 	template := loadTemplate(document)
 
-	var buffer bytes.Buffer
-	if template == nil {
-		document.EmitTo(&buffer)
-		document.EmitSignatureTo(&buffer)
-	} else {
-		err := template.Templates()[0].Execute(&buffer, document)
+	if *flag_format == "man" {
+		if !document.IsCommand {
+			fmt.Fprintf(os.Stderr, "A man page can only be generated for a command: %s\n", target)
+			os.Exit(1)
+		}
+		template = nil
+	}
+
+	if *flag_split != "" {
+		err := emitSplit(document, template, *flag_split, entry)
+		if err == nil {
+			err = cache.save()
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error running template: %v", err)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
 			os.Exit(1)
 		}
-		document.EmitSignatureTo(&buffer)
+		return
 	}
 
+	if flag_output != "" && flag_output != "-" {
+		document.outputDirectory = filepath.Dir(flag_output)
+	}
+	documentation, err := emitDocument(document, template)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running template: %v", err)
+		os.Exit(1)
+	}
+
+	writeDocumentation(documentation, entry)
+	err = cache.save()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeDocumentation writes to -output (or stdout), exiting on error.
+// The output is recorded in the cache entry, if any.
+func writeDocumentation(documentation string, entry *_cacheEntry) {
 	if debug {
 		// Skip printing if we're debugging
 		return
 	}
 
-	documentation := buffer.String()
-	documentation = strings.TrimSpace(documentation)
+	var err error
 	if flag_output == "" || flag_output == "-" {
-		fmt.Println(documentation)
+		_, err = fmt.Println(documentation)
 	} else {
-		file, err := os.Create(flag_output)
-		if err != nil {
-		}
-		defer file.Close()
-		_, err = fmt.Fprintln(file, documentation)
+		err = entry.write(flag_output, []byte(documentation+"\n"), *flag_mkdir)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
 	}
 }
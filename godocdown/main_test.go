@@ -2,13 +2,33 @@ package main
 
 import (
 	. "./terst"
+	"archive/zip"
 	"bytes"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
+// The tests keep their cache (see -cache) to themselves
+func TestMain(m *testing.M) {
+	directory, err := ioutil.TempDir("", "godocdown")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	*flag_cache = directory
+	code := m.Run()
+	os.RemoveAll(directory)
+	os.Exit(code)
+}
+
 func canTestImport() bool {
 	have, err := guessImportPath("../example")
 	Is(err, nil)
@@ -193,17 +213,21 @@ func Test(t *testing.T) {
 	is(`
 Package example is an example package with documentation
 
-    // Here is some code
-    func example() {
-    	abc := 1 + 1
-    }()
+` + "```" + `
+// Here is some code
+func example() {
+	abc := 1 + 1
+}()
+` + "```" + `
 
 ### Installation
 
-    # This is how to install it:
-    $ curl http://example.com
-    $ tar xf example.tar.gz -C .
-    $ ./example &
+` + "```sh" + `
+# This is how to install it:
+$ curl http://example.com
+$ tar xf example.tar.gz -C .
+$ ./example &
+` + "```" + `
 	`)
 
 	RenderStyle.IncludeSignature = true
@@ -233,7 +257,7 @@ Documentation for package issue3
 
 Nothing happens.
 
-    Some code happens.
+`+"```\nSome code happens.\n```"+`
 
 ## Usage
 
@@ -244,6 +268,1178 @@ Documentation for func Test()
 
 Something happens.
 
-    Some code happens.
+`+"```\nSome code happens.\n```"+`
     `))
 }
+
+func TestFormatHeading(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	Is(formatHeading(1, "example"), "# example")
+	Is(formatHeading(4, "func Example"), "#### func Example")
+
+	RenderStyle.HeadingOffset = 2
+	Is(formatHeading(1, "example"), "### example")
+	Is(formatHeading(6, "type ExampleType"), "###### type ExampleType")
+
+	RenderStyle.HeadingOffset = 0
+	RenderStyle.HeadingSetext = true
+	Is(formatHeading(1, "example"), "example\n=======")
+	Is(formatHeading(2, "Usage"), "Usage\n-----")
+	Is(formatHeading(3, "Installation"), "### Installation")
+
+	RenderStyle.HeadingOffset = 1
+	Is(formatHeading(1, "example"), "example\n-------")
+	Is(formatHeading(2, "Usage"), "### Usage")
+}
+
+func TestTemplateStyle(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	fsys := fstest.MapFS{
+		"a/a.go":                &fstest.MapFile{Data: []byte("// Package a is shifted\npackage a\n\n// T is a type\ntype T int\n")},
+		"a/.godocdown.template": &fstest.MapFile{Data: []byte("{{ headingOffset 2 }}{{ .Emit }}\n")},
+		"b/b.go":                &fstest.MapFile{Data: []byte("// Package b is not\npackage b\n")},
+	}
+	a, err := loadDocumentFS(fsys, "a", &build.Package{Dir: "a", ImportPath: "example.com/a"})
+	Is(err, nil)
+	b, err := loadDocumentFS(fsys, "b", &build.Package{Dir: "b", ImportPath: "example.com/b"})
+	Is(err, nil)
+
+	output, err := emitDocument(a, loadTemplate(a))
+	Is(err, nil)
+	Is(strings.HasPrefix(output, "### a\n"), true)
+	Is(RenderStyle.HeadingOffset, 0)
+	output, err = emitDocument(b, loadTemplate(b))
+	Is(err, nil)
+	Is(strings.HasPrefix(output, "# b\n"), true)
+
+	// Nor the type pages of -split
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+	Is(emitSplit(a, loadTemplate(a), directory, nil), nil)
+	index, err := ioutil.ReadFile(filepath.Join(directory, "README.md"))
+	Is(err, nil)
+	Is(strings.HasPrefix(string(index), "### a\n"), true)
+	page, err := ioutil.ReadFile(filepath.Join(directory, "T.md"))
+	Is(err, nil)
+	Is(strings.HasPrefix(string(page), "# a.T\n"), true)
+}
+
+func TestWrap(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	text := "The quick brown fox\njumps over the lazy dog.\n\nAnd then\nit sleeps.\n\n\tcode()\n"
+
+	RenderStyle.Width = 10
+	Is(formatIndent(text), "The quick\nbrown fox\njumps over\nthe lazy\ndog.\n\nAnd then\nit sleeps.\n\n```go\ncode()\n```\n")
+
+	RenderStyle.NoWrap = true
+	Is(formatIndent(text), "The quick brown fox jumps over the lazy dog.\n\nAnd then it sleeps.\n\n```go\ncode()\n```\n")
+
+	RenderStyle.NoWrap = false
+	RenderStyle.KeepLines = true
+	Is(formatIndent(text), "The quick brown fox\njumps over the lazy dog.\n\nAnd then\nit sleeps.\n\n```go\ncode()\n```\n")
+
+	// A kept line that looks like a heading, in the middle of a paragraph
+	text = "Tickets go to\nVery Important People\nfirst, then\neveryone\n---\n\nAdmission\n\nIs free.\n"
+	Is(headifySynopsis(formatIndent(text)), "Tickets go to\nVery Important People\nfirst, then\neveryone\n\\---\n\n\n### Admission\n\nIs free.\n")
+}
+
+func TestFenceLanguage(t *testing.T) {
+	Terst(t)
+
+	language := func(source string) string {
+		_, language := fenceLanguage(strings.SplitAfter(source, "\n"))
+		return language
+	}
+
+	Is(language("# Install it\n$ go get example.com/example\n"), "sh")
+	Is(language("{\n\t\"name\": \"example\"\n}\n"), "json")
+	Is(language("package example\n\nfunc Example() {}\n"), "go")
+	Is(language("func Example() {}\n"), "go")
+	Is(language("value := Example()\nfmt.Println(value)\n"), "go")
+	Is(language("Nothing happens here.\n"), "")
+	Is(language("Nothing\n"), "")
+	Is(language("See: Example\n"), "")
+	Is(language("// Nothing but a comment\n"), "")
+	Is(language("```yaml\nname: example\n"), "yaml")
+
+	lines, _ := fenceLanguage([]string{"```yaml\n", "name: example\n"})
+	Is(strings.Join(lines, ""), "name: example\n")
+
+	Is(outsideFence("Heading\n```\nHeading\n```\nHeading\n", strings.ToUpper), "HEADING\n```\nHeading\n```\nHEADING\n")
+
+	var buffer bytes.Buffer
+	writeCodeBlock(&buffer, []string{"$ godocdown .\n"})
+	Is(buffer.String(), "```sh\n$ godocdown .\n```\n")
+
+	// A fence longer than the backticks of the block
+	buffer.Reset()
+	writeCodeBlock(&buffer, []string{"```yaml\n", "name: example\n", "```\n", "````\n"})
+	Is(buffer.String(), "`````yaml\nname: example\n```\n````\n`````\n")
+	Is(outsideFence("Heading\n`````\nHeading\n```\nHeading\n`````\nHeading\n", strings.ToUpper),
+		"HEADING\n`````\nHeading\n```\nHeading\n`````\nHEADING\n")
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	Is(formatIndent("Returns *T or a List<T> | nil, see snake_case and _private.\n"),
+		"Returns \\*T or a List&lt;T> \\| nil, see snake_case and \\_private.\n")
+	Is(formatIndent("Use `a*b` or `x | y` instead.\n"), "Use `a*b` or `x | y` instead.\n")
+	// An unmatched backtick is literal, and leaves escaping on
+	Is(formatIndent("Use ` or a*b instead.\n"), "Use \\` or a\\*b instead.\n")
+	Is(formatIndent("Use `a*b` or ``c`d``, not a*b`.\n"), "Use `a*b` or ``c`d``, not a\\*b\\`.\n")
+	Is(formatIndent("Visit http://example.com/a_b#c_d for more.\n"), "Visit http://example.com/a_b#c_d for more.\n")
+	Is(formatIndent("# not a heading\n\n> not a quote\n\n1. not a list\n"), "\\# not a heading\n\n&gt; not a quote\n\n1\\. not a list\n")
+
+	RenderStyle.Width = 10
+	Is(formatIndent("Alpha beta - epsilon # delta\n"), "Alpha beta\n\\- epsilon\n\\# delta\n")
+
+	// A backslash is literal, and a run of - or = doesn't underline the line before it
+	RenderStyle = DefaultStyle
+	Is(formatIndent("Not \\*emphasis\\*, C:\\dir\n"), "Not \\\\\\*emphasis\\\\\\*, C:\\\\dir\n")
+	RenderStyle.KeepLines = true
+	Is(formatIndent("Some text\n===\nmore\n---\n"), "Some text\n\\===\nmore\n\\---\n")
+	Is(formatIndent("Before\n\n---\n\nAfter\n"), "Before\n\n\\---\n\nAfter\n")
+
+	RenderStyle = DefaultStyle
+	RenderStyle.EscapeMarkdown = false
+	Is(formatIndent("Returns **bold** | _emphasis_\n"), "Returns **bold** | _emphasis_\n")
+}
+
+func TestMarkdownComments(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	document, err := loadDocument(filepath.Join(".test", "markdown"))
+	Is(err, nil)
+	Is(document.MarkdownComments, true)
+
+	RenderStyle.MarkdownComments = document.MarkdownComments
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderSynopsisTo(buffer, document)
+	Is(strings.TrimSpace(buffer.String()), strings.TrimSpace(`
+Package markdown has documentation written in **Markdown**
+
+| Name | Value |
+|------|-------|
+| a_b  | 1     |
+
+Install it with go get.
+
+- first
+- second
+	`))
+
+	Is(formatIndent(filterText(document.pkg.Consts[0].Doc)), "Value is a *value*, see [the docs](http://example.com)\n")
+
+	document, err = loadDocument("../example")
+	Is(err, nil)
+	Is(document.MarkdownComments, false)
+}
+
+func TestGenerics(t *testing.T) {
+	Terst(t)
+
+	document, err := loadDocument(filepath.Join(".test", "generics"))
+	Is(err, nil)
+
+	typeList, constraintList := splitConstraints(document.pkg)
+	Is(len(typeList), 4)
+	Is(len(constraintList), 2)
+	Is(constraintList[0].Name, "Keyed")
+	Is(constraintList[1].Name, "Number")
+
+	Is(typeList[0].Name, "List")
+	Is(typeTypeParams(typeList[0]), "[T any]")
+	Is(typeList[0].Funcs[0].Name, "NewList")
+	Is(funcTypeParams(typeList[0].Funcs[0]), "[T any]")
+	Is(typeList[1].Name, "Lister")
+	Is(typeTypeParams(typeList[1]), "")
+	Is(typeList[2].Name, "Ordered") // Not cmp.Ordered
+	Is(typeList[3].Name, "Pair")
+	Is(typeTypeParams(typeList[3]), "[K comparable, V any]")
+	Is(typeList[3].Funcs[0].Name, "MakePair")
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderUsageTo(buffer, document)
+	Is(strings.Contains(buffer.String(), "#### func  Sum[N Number]\n"), true)
+	Is(strings.Contains(buffer.String(), "#### func (*List[T]) Push\n"), true)
+	Is(strings.Contains(buffer.String(), "### Constraints\n\n#### type Keyed\n"), true)
+}
+
+func TestImplements(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	RenderStyle.IncludeImplements = true
+
+	document, err := loadDocument(filepath.Join(".test", "implements"))
+	Is(err, nil)
+	IsNot(document.implements, nil)
+
+	Is(formatImplementsList(document.implements.implements["Names"]), "`Lister`, `fmt.Stringer`")
+	Is(formatImplementsList(document.implements.implements["Buffer"]), "`Lister` (as `*Buffer`), `io.Writer` (as `*Buffer`)")
+	Is(formatImplementsList(document.implements.implements["Failure"]), "`error`")
+	Is(formatImplementsList(document.implements.implementedBy["Lister"]), "`*Buffer`, `Names`")
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderUsageTo(buffer, document)
+	Is(strings.Contains(buffer.String(), "Lister lists things\n\nImplemented by: `*Buffer`, `Names`\n"), true)
+	Is(len(document.implements.errorList), 0)
+
+	// An import that can't be resolved is an error (reported), but not the end
+	document, err = loadDocument(filepath.Join(".test", "unresolved"))
+	Is(err, nil)
+	Is(len(document.implements.errorList) > 0, true)
+	Is(formatImplementsList(document.implements.implements["Name"]), "`fmt.Stringer`")
+
+	// Generic types are left out, and reported
+	document, err = loadDocument(filepath.Join(".test", "generics"))
+	Is(err, nil)
+	Is(len(document.implements.errorList), 0)
+	Is(strings.Join(document.implements.genericList, ", "), "List, Pair")
+	Is(len(document.implements.implements["List"]), 0)
+	Is(len(document.implements.implementedBy["Lister"]), 0)
+}
+
+func TestFields(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	RenderStyle.IncludeFields = true
+
+	document, err := loadDocument(filepath.Join(".test", "fields"))
+	Is(err, nil)
+
+	record := document.pkg.Types[1]
+	Is(record.Name, "Record")
+	fieldList := fieldsOf(record)
+	Is(len(fieldList), 5)
+	Is(fieldList[0].name, "Base")
+	Is(fieldList[0].embedded, true)
+	Is(fieldList[1].tag, `json:"name,omitempty" xml:"name"`)
+	Is(fieldList[3].name, "Count")
+	Is(fieldList[3].doc, "The size | count")
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderFieldsTo(buffer, record)
+	Is(strings.TrimSpace(buffer.String()), strings.TrimSpace("| Field | Type | Tag | Description |\n"+
+		"|-------|------|-----|-------------|\n"+
+		"| *Base* (embedded) | `Base` |  |  |\n"+
+		"| Name | `string` | `json:\"name,omitempty\" xml:\"name\"` | Name is the \\*name\\* of the record |\n"+
+		"| Size | `int` |  | The size \\| count |\n"+
+		"| Count | `int` |  | The size \\| count |\n"+
+		"| Parent | `*Record` |  |  |\n"))
+}
+
+func TestSplit(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	document, err := loadDocument("../example")
+	Is(err, nil)
+
+	err = emitSplit(document, nil, directory, nil)
+	Is(err, nil)
+	Is(document.split, false)
+
+	index, err := ioutil.ReadFile(filepath.Join(directory, "README.md"))
+	Is(err, nil)
+	Is(strings.Contains(string(index), "#### func  Example\n"), true)
+	Is(strings.Contains(string(index), "#### [type ExampleType](ExampleType.md)\n\nExampleType is a type of nothing\n"), true)
+	Is(strings.Contains(string(index), "func NewExample"), false)
+
+	page, err := ioutil.ReadFile(filepath.Join(directory, "ExampleType.md"))
+	Is(err, nil)
+	Is(strings.HasPrefix(string(page), "# example.ExampleType\n--\n[example](README.md)\n\n#### type ExampleType\n"), true)
+	Is(strings.Contains(string(page), "#### func  NewExample\n"), true)
+	Is(strings.Contains(string(page), "#### func (ExampleType) Set\n"), true)
+	Is(strings.Contains(string(page), "Types:"), false)
+
+	// A type named README doesn't overwrite the index
+	document, err = loadDocument(filepath.Join(".test", "readme"))
+	Is(err, nil)
+	err = emitSplit(document, nil, directory, nil)
+	Is(err, nil)
+
+	index, err = ioutil.ReadFile(filepath.Join(directory, "README.md"))
+	Is(err, nil)
+	Is(strings.Contains(string(index), "#### [type README](README.type.md)\n"), true)
+
+	page, err = ioutil.ReadFile(filepath.Join(directory, "README.type.md"))
+	Is(err, nil)
+	Is(strings.HasPrefix(string(page), "# readme.README\n--\n[readme](README.md)\n\nTypes: [Other](Other.md), README\n\n#### type README\n"), true)
+
+	page, err = ioutil.ReadFile(filepath.Join(directory, "Other.md"))
+	Is(err, nil)
+	Is(strings.Contains(string(page), "Types: Other, [README](README.type.md)\n"), true)
+}
+
+func TestSite(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	err = emitSite(filepath.Join(".test", "site"), directory)
+	Is(err, nil)
+
+	read := func(page string) string {
+		content, _ := ioutil.ReadFile(filepath.Join(directory, filepath.FromSlash(page)))
+		return string(content)
+	}
+
+	Is(read("README.md"), `# example.com/site
+--
+
+## Packages
+
+* [a](a/README.md) - Package a is a
+* [a/b](a/b/README.md) - Package b is below a
+* [c](c/README.md) - Package c is c
+`)
+
+	a := read("a/README.md")
+	Is(strings.HasPrefix(a, "[example.com/site](../README.md)\n\n# a\n--\n    import \"example.com/site/a\"\n"), true)
+	Is(strings.HasSuffix(a, "## Subpackages\n\n* [a/b](b/README.md) - Package b is below a\n\n## Imports\n\n* [c](../c/README.md) - Package c is c\n"), true)
+	// Identifiers of another package of the site link to its page
+	Is(strings.Contains(a, "A is [c.C](../c/README.md), which is [c.C](../c/README.md)\n"), true)
+
+	// The import path of .godocdown.import, rather than of the module
+	Is(strings.Contains(read("a/b/README.md"), "    import \"example.com/elsewhere/b\"\n"), true)
+
+	Is(strings.HasPrefix(read("a/b/README.md"), "[a](../README.md)\n"), true)
+	Is(strings.HasSuffix(read("c/README.md"), "## Imported By\n\n* [a](../a/README.md) - Package a is a\n"), true)
+
+	// The format of the template of one package is for its page only
+	directory = filepath.Join(directory, "siteformat")
+	err = emitSite(filepath.Join(".test", "siteformat"), directory)
+	Is(err, nil)
+	Is(strings.HasPrefix(read("a/README.md"), "[example.com/siteformat](../README.md)\n\n= a\n"), true)
+	Is(strings.HasPrefix(read("b/README.md"), "[example.com/siteformat](../README.md)\n\n# b\n"), true)
+	Is(strings.Contains(read("c/README.md"), "C is [b.B](../b/README.md)\n\n## Imports\n\n* [b](../b/README.md) - Package b is b\n"), true)
+	Is(strings.HasPrefix(read("README.md"), "# example.com/siteformat\n"), true)
+	_, err = os.Stat(filepath.Join(directory, "b", "README.adoc"))
+	Is(os.IsNotExist(err), true)
+	Is(RenderStyle.Format, "markdown")
+}
+
+func TestSiteFormat(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	RenderStyle.SiteFormat = "hugo"
+	err = emitSite(filepath.Join(".test", "site"), filepath.Join(directory, "hugo"))
+	Is(err, nil)
+	content, err := ioutil.ReadFile(filepath.Join(directory, "hugo", "a", "_index.md"))
+	Is(err, nil)
+	Is(strings.HasPrefix(string(content), "---\ntitle: \"a\"\nweight: 2\ndescription: \"Package a is a\"\n---\n\n[example.com/site](../)\n"), true)
+
+	RenderStyle.SiteFormat = "docusaurus"
+	err = emitSite(filepath.Join(".test", "site"), filepath.Join(directory, "docusaurus"))
+	Is(err, nil)
+	content, err = ioutil.ReadFile(filepath.Join(directory, "docusaurus", "c", "README.md"))
+	Is(err, nil)
+	Is(strings.HasPrefix(string(content), "---\ntitle: \"c\"\nsidebar_position: 4\ndescription: \"Package c is c\"\n---\n"), true)
+
+	RenderStyle.SiteFormat = "mkdocs"
+	err = emitSite(filepath.Join(".test", "site"), filepath.Join(directory, "mkdocs"))
+	Is(err, nil)
+	content, err = ioutil.ReadFile(filepath.Join(directory, "mkdocs", "mkdocs.nav.yml"))
+	Is(err, nil)
+	Is(string(content), `nav:
+  - "example.com/site": "README.md"
+  - "a": "a/README.md"
+  - "a/b": "a/b/README.md"
+  - "c": "c/README.md"
+`)
+}
+
+func TestMan(t *testing.T) {
+	Terst(t)
+
+	document, err := loadDocument(filepath.Join(".test", "command"))
+	Is(err, nil)
+	Is(document.IsCommand, true)
+	Is(len(document.flags), 3) // Not cfg.String
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderManTo(buffer, document)
+	Is(buffer.String(), `.TH COMMAND 1
+.SH NAME
+command \- Command command does nothing, quickly.
+.SH SYNOPSIS
+.B command
+\-fast input.txt
+.SH DESCRIPTION
+.PP
+Command command does nothing, quickly.
+.PP
+.RS 4
+.nf
+$ command \-fast input.txt
+.fi
+.RE
+.SS Options
+.PP
+It also reads
+\&.config files.
+.SH OPTIONS
+.TP
+\fB\-fast\fR=false
+Do nothing faster
+.TP
+\fB\-count\fR=3
+How many times to do nothing
+.TP
+\fB\-depth\fR=1
+How deep to do nothing
+`)
+}
+
+func TestFormat(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+
+	RenderStyle.Format = "asciidoc"
+	resetHeadings()
+	Is(formatHeading(1, "example"), "= example")
+	Is(formatHeading(2, "Usage"), "== Usage")
+	Is(renderer().CodeBlock("go", "func Example()"), "[source,go]\n----\nfunc Example()\n----")
+	Is(renderer().Link("type Record", "Record.adoc"), "xref:Record.adoc[type Record]")
+	Is(renderer().CodeSpan("int"), "`+int+`")
+	Is(escapeInline("a *b* snake_case _c_ http://example.com/_d_"), "a {asterisk}b{asterisk} snake_case &#95;c&#95; http://example.com/_d_")
+	Is(outsideFence("Heading\n----\nHeading\n----\nHeading\n", strings.ToUpper), "HEADING\n----\nHeading\n----\nHEADING\n")
+	Is(splitIndexFilename(), "README.adoc")
+
+	RenderStyle.Format = "rst"
+	resetHeadings()
+	Is(formatHeading(1, "example"), "example\n=======")
+	Is(formatHeading(2, "Usage"), "Usage\n-----")
+	Is(renderer().CodeBlock("go", "func Example()"), ".. code-block:: go\n\n    func Example()\n")
+	Is(renderer().CodeBlock("", "$ example"), "::\n\n    $ example\n")
+	Is(renderer().Link("type Record", "Record.rst"), ":doc:`type Record <Record>`")
+	Is(renderer().Link("example", "http://example.com"), "`example <http://example.com>`__")
+	Is(escapeInline("a *b* `c_d` e_ |f|"), "a \\*b\\* ``c_d`` e\\_ \\|f\\|")
+
+	RenderStyle.IncludeFields = true
+	document, err := loadDocument(filepath.Join(".test", "fields"))
+	Is(err, nil)
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderFieldsTo(buffer, document.pkg.Types[1])
+	Is(strings.Contains(buffer.String(), ".. list-table::\n   :header-rows: 1\n\n   * - Field\n"), true)
+	Is(strings.Contains(buffer.String(), "   * - Size\n     - ``int``\n     -\n     - The size \\| count\n"), true)
+
+	buffer = bytes.NewBuffer([]byte{})
+	renderHeaderTo(buffer, document)
+	Is(buffer.String(), "fields\n======\n\n.. code-block:: go\n\n    import \""+document.ImportPath+"\"\n\n")
+
+	// The headings of a document nest without skipping a level: a heading
+	// of the synopsis is at the level of the usage
+	RenderStyle = DefaultStyle
+	headingList := func(format, target string, heading *regexp.Regexp) string {
+		RenderStyle.Format = format
+		document, _ := loadDocument(target)
+		output, _ := emitDocument(document, nil)
+		return strings.Join(heading.FindAllString(output, -1), ", ")
+	}
+	Is(headingList("asciidoc", "../example", regexp.MustCompile(`(?m)^=+ \S+`)),
+		"= example, == Installation, == Usage, === func, === type, === func, === func")
+	Is(headingList("rst", "../example", regexp.MustCompile(`(?m)^\S+.*\n[=~^"'-]{3,}$`)),
+		"example\n=======, Installation\n------------, Usage\n-----, func  Example\n~~~~~~~~~~~~~, "+
+			"type ExampleType\n~~~~~~~~~~~~~~~~, func  NewExample\n~~~~~~~~~~~~~~~~, func (ExampleType) Set\n~~~~~~~~~~~~~~~~~~~~~~")
+
+	// The constraints (3) enclose their types (4), which are otherwise right below the usage (2)
+	Is(headingList("asciidoc", filepath.Join(".test", "generics"), regexp.MustCompile(`(?m)^=+ (?:Usage|Constraints|type Pair|type Keyed)`)),
+		"== Usage, === type Pair, === Constraints, ==== type Keyed")
+}
+
+type _testRenderer struct {
+	_gfmRenderer
+}
+
+func (_testRenderer) Heading(level int, text string) string {
+	return fmt.Sprintf("<h%d>%s</h%d>", level, text, level)
+}
+
+func TestRenderer(t *testing.T) {
+	Terst(t)
+
+	defer func() {
+		RenderStyle = DefaultStyle
+		delete(rendererMap, "test")
+	}()
+
+	RenderStyle.Format = "plain"
+	Is(renderer().CodeBlock("go", "func Example()"), "    func Example()\n")
+	Is(renderer().Table([]string{"Field", "Type", "Description"}, [][]string{
+		{"Name", "`string`", "The name"},
+		{"Size", "`int`", ""},
+	}), "* Name `string`: The name\n* Size `int`\n")
+
+	// A custom style only overrides what it needs
+	rendererMap["test"] = _testRenderer{}
+	RenderStyle.Format = "test"
+	RenderStyle.HeadingOffset = 1
+	Is(formatHeading(RenderStyle.UsageHeader, "Usage"), "<h3>Usage</h3>")
+	Is(renderer().CodeBlock("go", "func Example()"), "```go\nfunc Example()\n```")
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderHeaderTo(buffer, &_document{Name: "example", ImportPath: "example.com/example"})
+	Is(buffer.String(), "<h2>example</h2>\n--\n    import \"example.com/example\"\n\n")
+}
+
+func TestDiff(t *testing.T) {
+	Terst(t)
+
+	name, oldAPI, err := loadAPI(filepath.Join(".test", "diff", "old"), ".")
+	Is(err, nil)
+	Is(name, "diff")
+	Is(oldAPI["variable Default"].declaration, "var Default Record")
+	_, newAPI, err := loadAPI(filepath.Join(".test", "diff", "new"), ".")
+	Is(err, nil)
+
+	addedList, removedList, changedList := diffAPI(oldAPI, newAPI)
+	Is(len(addedList), 3)
+	Is(addedList[0].key(), "constant Between")
+	Is(addedList[1].key(), "method Record.Flush")
+	Is(addedList[2].key(), "field Record.Tag")
+	Is(len(removedList), 1)
+	Is(removedList[0].key(), "function Remove")
+	// Second and Third moved (First didn't, the unexported hidden counts)
+	Is(len(changedList), 5)
+	Is(changedList[0][1].key(), "constant Second")
+	Is(changedList[0][0].declaration, "const Second = iota // iota = 2")
+	Is(changedList[0][1].declaration, "const Second = iota // iota = 3")
+	Is(changedList[1][1].key(), "constant Size")
+	Is(changedList[2][1].key(), "constant Third")
+	Is(changedList[3][1].key(), "function Open")
+	Is(changedList[4][1].key(), "field Record.Count")
+
+	buffer := bytes.NewBuffer([]byte{})
+	renderDiffTo(buffer, name, "old", "new", oldAPI, newAPI)
+	Is(strings.Contains(buffer.String(), "### Added\n\n* constant `Between`\n* method `Record.Flush`\n* field `Record.Tag`\n"), true)
+	Is(strings.Contains(buffer.String(), "### Removed\n\n* function `Remove`\n"), true)
+	Is(strings.Contains(buffer.String(), "#### function `Open`\n\n"+
+		"```diff\n- func Open(name string) *Record\n+ func Open(name string, create bool) (*Record, error)\n```\n"), true)
+
+	buffer = bytes.NewBuffer([]byte{})
+	renderDiffTo(buffer, name, "old", "old", oldAPI, oldAPI)
+	Is(strings.Contains(buffer.String(), "No changes."), true)
+}
+
+func TestRevision(t *testing.T) {
+	Terst(t)
+
+	document, err := loadRevision(filepath.Join("..", "example"), "HEAD")
+	Is(err, nil)
+	Is(document.Name, "example")
+	Is(strings.Contains(document.Emit(), "#### func  Example\n"), true)
+
+	_, err = loadRevision(filepath.Join("..", "example"), "no-such-revision")
+	IsNot(err, nil)
+
+	// go.mod and LICENSE come from the revision, not the working tree
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+	Is(os.Mkdir(filepath.Join(directory, "sub"), 0755), nil)
+	for name, content := range map[string]string{
+		"go.mod":     "module github.com/user/project\n\ngo 1.20\n",
+		"LICENSE":    "MIT License\n\nPermission is hereby granted, free of charge, to any person\n",
+		"sub/sub.go": "// Package sub is committed\npackage sub\n",
+	} {
+		Is(ioutil.WriteFile(filepath.Join(directory, filepath.FromSlash(name)), []byte(content), 0644), nil)
+	}
+	_, err = git(directory, "init", "-q")
+	Is(err, nil)
+	_, err = git(directory, "add", ".")
+	Is(err, nil)
+	_, err = git(directory, "-c", "user.name=godocdown", "-c", "user.email=godocdown@example.com", "commit", "-q", "-m", "Commit")
+	Is(err, nil)
+	Is(ioutil.WriteFile(filepath.Join(directory, "go.mod"), []byte("module github.com/user/project\n\ngo 1.22\n"), 0644), nil)
+	Is(ioutil.WriteFile(filepath.Join(directory, "LICENSE"), []byte("All rights reserved\n"), 0644), nil)
+
+	document, err = loadRevision(filepath.Join(directory, "sub"), "HEAD")
+	Is(err, nil)
+	module := findModule(document)
+	Is(module.goVersion, "1.20")
+	Is(module.packagePath, "sub")
+	Is(resolveImportPath(document, module), "github.com/user/project/sub")
+	badges := document.EmitBadges()
+	Is(strings.Contains(badges, "License: MIT"), true)
+	Is(strings.Contains(badges, "Go 1.20"), true)
+
+	// Without a go.mod in the revision, there is no module
+	Is(os.Remove(filepath.Join(directory, "go.mod")), nil)
+	_, err = git(directory, "rm", "-q", "--cached", "go.mod")
+	Is(err, nil)
+	_, err = git(directory, "-c", "user.name=godocdown", "-c", "user.email=godocdown@example.com", "commit", "-q", "-m", "Remove go.mod")
+	Is(err, nil)
+	Is(ioutil.WriteFile(filepath.Join(directory, "go.mod"), []byte("module github.com/user/project\n\ngo 1.22\n"), 0644), nil)
+	document, err = loadRevision(filepath.Join(directory, "sub"), "HEAD")
+	Is(err, nil)
+	Is(findModule(document) == nil, true)
+
+	// A package that is gone from the working tree, but not the revision
+	Is(os.RemoveAll(filepath.Join(directory, "sub")), nil)
+	document, err = loadRevision(filepath.Join(directory, "sub"), "HEAD~1")
+	Is(err, nil)
+	Is(document.Name, "sub")
+	Is(document.ImportPath, "github.com/user/project/sub")
+	Is(strings.Contains(document.Emit(), "Package sub is committed"), true)
+	_, err = loadRevision(filepath.Join(directory, "nothing"), "HEAD~1")
+	IsNot(err, nil)
+}
+
+func TestLint(t *testing.T) {
+	Terst(t)
+
+	Is(startsWithName("Example is a function", "Example"), true)
+	Is(startsWithName("An Example of a function", "Example"), true)
+	Is(startsWithName("A does something", "A"), true)
+	Is(startsWithName("Examples are functions", "Example"), false)
+	Is(startsWithName("This is a function", "Example"), false)
+
+	document, err := loadDocument(filepath.Join(".test", "implements"))
+	Is(err, nil)
+	report := lintDocument(document)
+	Is(report.total, 10)
+	Is(report.documented, 9)
+	Is(len(report.issueList), 1)
+	Is(report.issueList[0], filepath.Join(".test", "implements", "implements.go")+":45: func (Failure) Error: missing doc comment")
+
+	output, pass, err := emitLint(filepath.Join(".test", "site")+"/...", 100)
+	Is(err, nil)
+	Is(pass, true)
+	Is(output, ".test/site/a: 100.0% documented (2/2)\n.test/site/a/b: 100.0% documented (1/1)\n.test/site/c: 100.0% documented (2/2)")
+
+	_, pass, err = emitLint(filepath.Join(".test", "fields"), 90)
+	Is(err, nil)
+	Is(pass, false)
+}
+
+func TestWriteFile(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	filename := filepath.Join(directory, "README.markdown")
+	Is(writeFile(filename, []byte("first\n"), false), nil)
+	content, _ := ioutil.ReadFile(filename)
+	Is(string(content), "first\n")
+
+	// An existing file keeps its mode
+	Is(os.Chmod(filename, 0600), nil)
+	Is(writeFile(filename, []byte("second\n"), false), nil)
+	content, _ = ioutil.ReadFile(filename)
+	Is(string(content), "second\n")
+	info, err := os.Stat(filename)
+	Is(err, nil)
+	Is(info.Mode().Perm(), os.FileMode(0600))
+
+	// Through a symbolic link
+	link := filepath.Join(directory, "README.md")
+	Is(os.Symlink(filename, link), nil)
+	Is(writeFile(link, []byte("third\n"), false), nil)
+	info, err = os.Lstat(link)
+	Is(err, nil)
+	Is(info.Mode()&os.ModeSymlink != 0, true)
+	content, _ = ioutil.ReadFile(filename)
+	Is(string(content), "third\n")
+
+	// A missing parent directory, unless asked to create it
+	nested := filepath.Join(directory, "docs", "api", "README.markdown")
+	IsNot(writeFile(nested, []byte("fourth\n"), false), nil)
+	Is(writeFile(nested, []byte("fourth\n"), true), nil)
+	content, _ = ioutil.ReadFile(nested)
+	Is(string(content), "fourth\n")
+
+	// Nothing is left behind
+	list, err := ioutil.ReadDir(directory)
+	Is(err, nil)
+	Is(len(list), 3)
+}
+
+func TestCache(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	cache := loadCache(directory)
+	entry, fresh := cache.lookup("../example")
+	Is(fresh, false)
+	document, err := loadDocument("../example")
+	Is(err, nil)
+	Is(emitSplit(document, nil, directory, entry), nil)
+	Is(len(entry.Output), 2)
+	Is(cache.save(), nil)
+
+	// Kept under -cache, not in the output directory
+	_, err = os.Stat(filepath.Join(*flag_cache, kilt.Sha1([]byte(cache.Directory))+".json"))
+	Is(err, nil)
+	fileList, err := ioutil.ReadDir(directory)
+	Is(err, nil)
+	Is(len(fileList), 2)
+
+	// Unchanged
+	cache = loadCache(directory)
+	_, fresh = cache.lookup("../example")
+	Is(fresh, true)
+
+	// The style is hashed by field, not by (say) the address of a regular expression
+	style := styleInput(DefaultStyle)
+	Is(strings.Contains(style, "SynopsisHeading="+synopsisHeadingTitleCase1Word_Regexp.String()+"\n"), true)
+	Is(strings.Contains(style, "0x"), false)
+	Is(styleInput(DefaultStyle), style)
+
+	// A different option
+	RenderStyle.Width = 60
+	_, fresh = cache.lookup("../example")
+	RenderStyle = DefaultStyle
+	Is(fresh, false)
+
+	// An output that was changed since
+	cache = loadCache(directory)
+	Is(ioutil.WriteFile(filepath.Join(directory, "ExampleType.md"), []byte("\n"), 0644), nil)
+	_, fresh = cache.lookup("../example")
+	Is(fresh, false)
+
+	// The page of a type that is gone is removed, unless it was changed since
+	source := filepath.Join(directory, "source")
+	output := filepath.Join(directory, "output")
+	Is(os.Mkdir(source, 0755), nil)
+	split := func(content string) error {
+		err := ioutil.WriteFile(filepath.Join(source, "source.go"), []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+		cache := loadCache(output)
+		entry, _ := cache.lookup(source)
+		document, err := loadDocument(source)
+		if err != nil {
+			return err
+		}
+		err = emitSplit(document, nil, output, entry)
+		if err != nil {
+			return err
+		}
+		return cache.save()
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(output, name))
+		return err == nil
+	}
+	Is(split("package source\n\ntype A int\n\ntype B int\n\ntype C int\n"), nil)
+	Is(exists("B.md"), true)
+	Is(ioutil.WriteFile(filepath.Join(output, "C.md"), []byte("Edited\n"), 0644), nil)
+	Is(split("package source\n\ntype A int\n\ntype D int\n"), nil)
+	Is(exists("A.md"), true)
+	Is(exists("B.md"), false)
+	Is(exists("C.md"), true)
+	Is(exists("D.md"), true)
+
+	// The go.mod and LICENSE of the module, for badges
+	Is(os.Mkdir(filepath.Join(directory, "sub"), 0755), nil)
+	for name, content := range map[string]string{
+		"go.mod":     "module example.com/cached\n\ngo 1.20\n",
+		"LICENSE":    "MIT License\n",
+		"sub/sub.go": "// Package sub is cached\npackage sub\n",
+	} {
+		Is(ioutil.WriteFile(filepath.Join(directory, filepath.FromSlash(name)), []byte(content), 0644), nil)
+	}
+	subdirectory := filepath.Join(directory, "sub")
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+	RenderStyle.IncludeBadges = true
+	before := inputHash(subdirectory)
+	Is(ioutil.WriteFile(filepath.Join(directory, "go.mod"), []byte("module example.com/cached\n\ngo 1.22\n"), 0644), nil)
+	afterModule := inputHash(subdirectory)
+	Is(afterModule != before, true)
+	Is(ioutil.WriteFile(filepath.Join(directory, "LICENSE"), []byte("All rights reserved\n"), 0644), nil)
+	Is(inputHash(subdirectory) != afterModule, true)
+	RenderStyle.IncludeBadges = false
+	for _, include := range []*bool{&RenderStyle.IncludeInstall, &RenderStyle.IncludeDependencies} {
+		*include = true
+		before = inputHash(subdirectory)
+		Is(ioutil.WriteFile(filepath.Join(directory, "go.mod"), []byte("module example.com/cached\n\ngo 1.20\n"), 0644), nil)
+		after := inputHash(subdirectory)
+		Is(ioutil.WriteFile(filepath.Join(directory, "go.mod"), []byte("module example.com/cached\n\ngo 1.22\n"), 0644), nil)
+		*include = false
+		Is(after != before, true)
+	}
+	Is(moduleInput(subdirectory) != "", true)
+	unbadged := inputHash(subdirectory)
+	Is(ioutil.WriteFile(filepath.Join(directory, "LICENSE"), []byte("MIT License\n"), 0644), nil)
+	Is(inputHash(subdirectory), unbadged)
+}
+
+func TestSourceFile(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	// Only the given file, not the rest of the package
+	filename := filepath.Join(directory, "a.go")
+	Is(ioutil.WriteFile(filename, []byte("// Package single is a single file\npackage single\n\n// A is a\nfunc A() {}\n"), 0644), nil)
+	Is(ioutil.WriteFile(filepath.Join(directory, "b.go"), []byte("package single\n\n// B is b\nfunc B() {}\n"), 0644), nil)
+	document, err := loadDocument(filename)
+	Is(err, nil)
+	Is(document.Name, "single")
+	output := document.Emit()
+	Is(strings.Contains(output, "#### func  A\n"), true)
+	Is(strings.Contains(output, "func  B"), false)
+	// Not import "." either
+	Is(document.ImportPath, "")
+	Is(strings.Contains(output, "import"), false)
+
+	// Source on stdin
+	stdin := os.Stdin
+	defer func() {
+		os.Stdin = stdin
+	}()
+	file, err := os.Open(filepath.Join(directory, "b.go"))
+	Is(err, nil)
+	defer file.Close()
+	os.Stdin = file
+
+	document, err = loadDocument("-")
+	Is(err, nil)
+	Is(document.Name, "single")
+	output = document.Emit()
+	Is(strings.Contains(output, "#### func  B\n"), true)
+	// Not import "."
+	Is(document.ImportPath, "")
+	Is(strings.Contains(output, "import"), false)
+
+	// Source on stdin, in the directory of a module
+	working, err := os.Getwd()
+	Is(err, nil)
+	defer os.Chdir(working)
+	Is(os.Chdir(directory), nil)
+	Is(ioutil.WriteFile("go.mod", []byte("module example.com/single\n"), 0644), nil)
+	file.Seek(0, 0)
+
+	document, err = loadDocument("-")
+	Is(err, nil)
+	Is(document.ImportPath, "example.com/single")
+
+	// A single file, in the directory of a module
+	document, err = loadDocument(filename)
+	Is(err, nil)
+	Is(document.ImportPath, "example.com/single")
+	Is(strings.Contains(document.Emit(), "    import \"example.com/single\"\n"), true)
+}
+
+func TestArchive(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+
+	// A module zip, as from a module proxy
+	archive := filepath.Join(directory, "v1.0.0.zip")
+	file, err := os.Create(archive)
+	Is(err, nil)
+	writer := zip.NewWriter(file)
+	for name, content := range map[string]string{
+		"example.com/m@v1.0.0/go.mod":              "module example.com/m\n",
+		"example.com/m@v1.0.0/sub/sub.go":          "// Package sub is zipped\npackage sub\n",
+		"example.com/m@v1.0.0/sub/.godocdown.tmpl": "{{ .EmitHeader }}\n\nFrom the zip\n",
+	} {
+		entry, err := writer.Create(name)
+		Is(err, nil)
+		entry.Write([]byte(content))
+	}
+	Is(writer.Close(), nil)
+	Is(file.Close(), nil)
+
+	document, err := loadDocument(archive + "/sub")
+	Is(err, nil)
+	Is(document.Name, "sub")
+	output, err := emitDocument(document, loadTemplate(document))
+	Is(err, nil)
+	Is(output, "# sub\n--\n    import \"example.com/m/sub\"\n\nFrom the zip")
+
+	// Under a directory that only looks like an archive
+	nested := filepath.Join(directory, "x.zip.d", "v1.0.0.zip")
+	Is(os.MkdirAll(filepath.Dir(nested), 0755), nil)
+	Is(os.Rename(archive, nested), nil)
+	archive, subdirectory, ok := splitArchive(nested + "/sub")
+	Is(ok, true)
+	Is(archive, nested)
+	Is(subdirectory, "sub")
+	document, err = loadDocument(nested + "/sub")
+	Is(err, nil)
+	Is(document.ImportPath, "example.com/m/sub")
+	_, _, ok = splitArchive(filepath.Join(directory, "x.zip.d"))
+	Is(ok, false)
+
+	// A git revision
+	tree, treeDirectory, err := newGitTree("../example", "HEAD")
+	Is(err, nil)
+	Is(treeDirectory, "example")
+	source, err := fs.ReadFile(tree, "example/example.go")
+	Is(err, nil)
+	onDisk, err := ioutil.ReadFile("../example/example.go")
+	Is(err, nil)
+	Is(string(source), string(onDisk))
+	example, err := fs.Sub(tree, "example")
+	Is(err, nil)
+	Is(fstest.TestFS(example, "example.go"), nil)
+	_, err = fs.ReadFile(tree, "example/nothing.go")
+	Is(err != nil, true)
+	_, _, err = newGitTree("../example", "no-such-revision")
+	Is(err != nil, true)
+
+	// In memory
+	document, err = loadDocumentFS(fstest.MapFS{
+		"a/a.go":              &fstest.MapFile{Data: []byte("// Package a is in memory\npackage a\n")},
+		"a/.godocdown.import": &fstest.MapFile{Data: []byte("example.com/a/v2\n")},
+	}, "a", &build.Package{Dir: "a", ImportPath: "example.com/a"})
+	Is(err, nil)
+	Is(document.ImportPath, "example.com/a/v2")
+	Is(document.pkg.Doc, "Package a is in memory\n")
+}
+
+func TestChoosePackage(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+	directory = filepath.Join(directory, "library")
+	Is(os.Mkdir(directory, 0755), nil)
+
+	for name, content := range map[string]string{
+		"library.go": "// Package library is the library\npackage library\n",
+		"other.go":   "// Package other is another package\npackage other\n",
+		"gen.go":     "//go:build ignore\n\n// Gen generates\npackage main\n",
+		"shadow.go":  "// Package library_test is not a test file\npackage library_test\n",
+	} {
+		Is(ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0644), nil)
+	}
+
+	for count := 0; count < 8; count++ {
+		document, err := loadDocument(directory)
+		Is(err, nil)
+		Is(document.Name, "library")
+		Is(document.IsCommand, false)
+	}
+
+	defer func() {
+		*flag_package = ""
+	}()
+	*flag_package = "main"
+	document, err := loadDocument(directory)
+	Is(err, nil)
+	Is(document.Name, "library")
+	Is(document.IsCommand, true)
+	Is(document.pkg.Doc, "Gen generates\n")
+
+	*flag_package = "missing"
+	document, err = loadDocument(directory)
+	IsNot(err, nil)
+	Is(document == nil, true)
+
+	// Never an external test package, even when asked
+	*flag_package = "library_test"
+	document, err = loadDocument(directory)
+	IsNot(err, nil)
+	Is(document == nil, true)
+
+	// Without the library, any other package before main
+	*flag_package = ""
+	Is(os.Remove(filepath.Join(directory, "library.go")), nil)
+	document, err = loadDocument(directory)
+	Is(err, nil)
+	Is(document.Name, "other")
+}
+
+func TestExamples(t *testing.T) {
+	Terst(t)
+
+	document, err := loadDocument(filepath.Join(".test", "examples"))
+	Is(err, nil)
+	Is(document.Name, "examples")
+	Is(len(document.pkg.Examples), 1)
+	Is(len(document.pkg.Funcs), 1)
+	Is(document.pkg.Funcs[0].Examples[0].Suffix, "alice")
+	Is(len(document.pkg.Types[0].Examples), 1)
+	Is(len(document.pkg.Types[0].Methods[0].Examples), 1)
+
+	output := document.Emit()
+	Is(strings.Contains(output, "Package examples has examples in its tests\n\n*Example:*\n\n```go\nexamples.Hello(\"World\")\n```\n\nOutput:\n\n```\nHello, World\n```\n"), true)
+	Is(strings.Contains(output, "Hello says hello\n\n*Example (alice):*\n\nWith a different name\n\n```go\n// Say hello\nexamples.Hello(\"Alice\")\n```\n"), true)
+	Is(strings.Contains(output, "Greet greets with Hello\n\n*Example:*\n\n```go\nexamples.Greeter{Name: \"Bob\"}.Greet()\n```"), true)
+	Is(strings.Contains(output, "TestHelper"), false)
+
+	RenderStyle.IncludeExamples = false
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+	Is(strings.Contains(document.Emit(), "Example"), false)
+}
+
+func TestBadges(t *testing.T) {
+	Terst(t)
+
+	Is(detectLicense("MIT License\n\nPermission is hereby granted, free\nof charge, to any person"), "MIT")
+	Is(detectLicense("Apache License\nVersion 2.0, January 2004"), "Apache-2.0")
+	Is(detectLicense("Redistribution and use in source and binary forms ... Neither the name"), "BSD-3-Clause")
+	Is(detectLicense("All rights reserved"), "")
+	Is(shieldsBadge("license", "BSD-3-Clause", "blue"), "https://img.shields.io/badge/license-BSD--3--Clause-blue.svg")
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+	Is(os.Mkdir(filepath.Join(directory, "sub"), 0755), nil)
+	for name, content := range map[string]string{
+		"go.mod":     "module github.com/user/project\n\ngo 1.21\n",
+		"LICENSE":    "MIT License\n\nPermission is hereby granted, free of charge, to any person\n",
+		"sub/sub.go": "// Package sub is below\npackage sub\n",
+	} {
+		Is(ioutil.WriteFile(filepath.Join(directory, filepath.FromSlash(name)), []byte(content), 0644), nil)
+	}
+
+	document, err := loadDocument(filepath.Join(directory, "sub"))
+	Is(err, nil)
+	module := findModule(document)
+	Is(module.path, "github.com/user/project")
+	Is(module.goVersion, "1.21")
+	Is(module.relative, "..")
+	Is(module.packagePath, "sub")
+
+	RenderStyle.CIBadge = "https://github.com/{repository}/actions/workflows/go.yml/badge.svg"
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+	Is(document.EmitBadges(), "[![Go Reference](https://pkg.go.dev/badge/github.com/user/project/sub.svg)](https://pkg.go.dev/github.com/user/project/sub) "+
+		"[![Go Report Card](https://goreportcard.com/badge/github.com/user/project)](https://goreportcard.com/report/github.com/user/project) "+
+		"[![License: MIT](https://img.shields.io/badge/license-MIT-blue.svg)](../LICENSE) "+
+		"[![Go 1.21](https://img.shields.io/badge/go-%3E=1.21-00ADD8.svg)](https://go.dev/dl/) "+
+		"[![CI](https://github.com/user/project/actions/workflows/go.yml/badge.svg)](https://github.com/user/project/actions/workflows/go.yml/badge.svg)")
+
+	Is(strings.Contains(document.EmitHeader(), "[![Go Reference]"), false)
+	RenderStyle.IncludeBadges = true
+	Is(strings.Contains(document.EmitHeader(), "[![Go Reference]"), true)
+
+	// The license is linked from where the documentation is written
+	document.outputDirectory = directory
+	Is(strings.Contains(document.EmitBadges(), "](LICENSE)"), true)
+	document.outputDirectory = filepath.Join(directory, "docs", "api")
+	Is(strings.Contains(document.EmitBadges(), "](../../LICENSE)"), true)
+	document.outputDirectory = ""
+	Is(emitSplit(document, nil, filepath.Join(directory, "docs", "sub"), nil), nil)
+	index, err := ioutil.ReadFile(filepath.Join(directory, "docs", "sub", "README.md"))
+	Is(err, nil)
+	Is(strings.Contains(string(index), "](../../LICENSE)"), true)
+	Is(document.outputDirectory, "")
+
+	// Once, where a template places them, or else in the header
+	template := filepath.Join(directory, "sub", ".godocdown.template")
+	Is(ioutil.WriteFile(template, []byte("{{ .EmitHeader }}\n\n{{ if true }}{{ .EmitBadges }}{{ end }}\n"), 0644), nil)
+	output, err := emitDocument(document, loadTemplate(document))
+	Is(err, nil)
+	Is(strings.Count(output, "[![Go Reference]"), 1)
+	Is(strings.HasPrefix(output, "# sub\n--\n"), true)
+	Is(strings.Contains(output, "\n\n[![Go Reference]"), true)
+	Is(document.badgesPlaced, false)
+	Is(ioutil.WriteFile(template, []byte("{{ .Emit }}\n"), 0644), nil)
+	output, err = emitDocument(document, loadTemplate(document))
+	Is(err, nil)
+	Is(strings.Count(output, "[![Go Reference]"), 1)
+}
+
+func TestInstall(t *testing.T) {
+	Terst(t)
+
+	directory, err := ioutil.TempDir("", "godocdown")
+	Is(err, nil)
+	defer os.RemoveAll(directory)
+	Is(os.MkdirAll(filepath.Join(directory, "cmd", "tool"), 0755), nil)
+	for name, content := range map[string]string{
+		"go.mod": "module github.com/user/project\n\ngo 1.21\n\nrequire github.com/a/b v1.2.3\n\n" +
+			"require (\n\tgithub.com/c/d v0.1.0 // indirect\n\t\"github.com/e/f\" v2.0.0+incompatible\n)\n",
+		"project.go":       "// Package project is a library\npackage project\n",
+		"cmd/tool/main.go": "// Tool is a command\npackage main\n\nfunc main() {}\n",
+	} {
+		Is(ioutil.WriteFile(filepath.Join(directory, filepath.FromSlash(name)), []byte(content), 0644), nil)
+	}
+
+	document, err := loadDocument(directory)
+	Is(err, nil)
+	module := findModule(document)
+	Is(len(module.requireList), 3)
+	Is(module.requireList[1].indirect, true)
+	Is(module.requireList[2].path, "github.com/e/f")
+
+	Is(document.EmitInstall(), "## Installation\n\n```sh\ngo get github.com/user/project@latest\n```\n\nRequires Go 1.21 or later.")
+	Is(document.EmitDependencies(), "## Dependencies\n\n* `github.com/a/b` v1.2.3\n* `github.com/e/f` v2.0.0+incompatible")
+
+	RenderStyle.IncludeInstall = true
+	RenderStyle.IncludeDependencies = true
+	defer func() {
+		RenderStyle = DefaultStyle
+	}()
+	output := document.Emit()
+	Is(strings.Contains(output, "Package project is a library\n\n## Installation\n"), true)
+	Is(strings.HasSuffix(output, "## Dependencies\n\n* `github.com/a/b` v1.2.3\n* `github.com/e/f` v2.0.0+incompatible"), true)
+
+	document, err = loadDocument(filepath.Join(directory, "cmd", "tool"))
+	Is(err, nil)
+	Is(strings.Contains(document.Emit(), "```sh\ngo install github.com/user/project/cmd/tool@latest\n```\n"), true)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A flag defined by a command, found by looking for calls like:
+//
+//	flag.String("name", "default", "usage")
+//	flag.StringVar(&value, "name", "default", "usage")
+type _flag struct {
+	name  string
+	value string
+	usage string
+}
+
+var flagFunctionList = map[string]bool{
+	"Bool": true, "Duration": true, "Float64": true, "Int": true, "Int64": true,
+	"String": true, "Uint": true, "Uint64": true, "Func": true, "TextVar": true,
+}
+
+// flagPackageName returns the name the flag package is imported as by file,
+// or "" if it isn't
+func flagPackageName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == "flag" {
+			if spec.Name != nil {
+				return spec.Name.Name
+			}
+			return "flag"
+		}
+	}
+	return ""
+}
+
+// isFlagSet reports whether node is a *flag.FlagSet (or its type), with
+// the flag package imported as flagPackage: flag.NewFlagSet(...),
+// flag.CommandLine, &flag.FlagSet{}, or *flag.FlagSet
+func isFlagSet(node ast.Expr, flagPackage string) bool {
+	isFlagPackage := func(node ast.Expr) bool {
+		ident, ok := node.(*ast.Ident)
+		return ok && flagPackage != "" && ident.Name == flagPackage
+	}
+	switch node := node.(type) {
+	case *ast.StarExpr:
+		return isFlagSet(node.X, flagPackage)
+	case *ast.UnaryExpr:
+		return node.Op == token.AND && isFlagSet(node.X, flagPackage)
+	case *ast.CompositeLit:
+		return isFlagSet(node.Type, flagPackage)
+	case *ast.CallExpr:
+		selector, ok := node.Fun.(*ast.SelectorExpr)
+		return ok && isFlagPackage(selector.X) && selector.Sel.Name == "NewFlagSet"
+	case *ast.SelectorExpr:
+		return isFlagPackage(node.X) && (node.Sel.Name == "FlagSet" || node.Sel.Name == "CommandLine")
+	}
+	return false
+}
+
+// findFlagSets returns the names of the variables (and parameters) of file
+// that are a *flag.FlagSet
+func findFlagSets(file *ast.File, nameSet map[string]bool) {
+	flagPackage := flagPackageName(file)
+	if flagPackage == "" {
+		return
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.ValueSpec:
+			for index, name := range node.Names {
+				if (node.Type != nil && isFlagSet(node.Type, flagPackage)) || (index < len(node.Values) && isFlagSet(node.Values[index], flagPackage)) {
+					nameSet[name.Name] = true
+				}
+			}
+		case *ast.AssignStmt:
+			for index, left := range node.Lhs {
+				if ident, ok := left.(*ast.Ident); ok && len(node.Lhs) == len(node.Rhs) && isFlagSet(node.Rhs[index], flagPackage) {
+					nameSet[ident.Name] = true
+				}
+			}
+		case *ast.Field:
+			if isFlagSet(node.Type, flagPackage) {
+				for _, name := range node.Names {
+					nameSet[name.Name] = true
+				}
+			}
+		}
+		return true
+	})
+}
+
+// findFlags returns the flags defined in the package (which must not have
+// been trimmed by doc.New yet, since flags are often defined in main): the
+// calls of the flag package, or of a *flag.FlagSet (by the name of its
+// variable, which is close enough), but not of anything else with a String
+// method, say.
+func findFlags(fset *token.FileSet, pkg *ast.Package) []_flag {
+	nameList := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+
+	flagSetSet := map[string]bool{}
+	for _, name := range nameList {
+		findFlagSets(pkg.Files[name], flagSetSet)
+	}
+
+	var flagList []_flag
+	seen := map[string]bool{}
+	for _, name := range nameList {
+		flagPackage := flagPackageName(pkg.Files[name])
+		ast.Inspect(pkg.Files[name], func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				if ident.Name != flagPackage && !flagSetSet[ident.Name] {
+					return true
+				}
+			} else if !isFlagSet(selector.X, flagPackage) {
+				return true
+			}
+			function := selector.Sel.Name
+			arguments := call.Args
+			if strings.HasSuffix(function, "Var") && function != "TextVar" {
+				function = strings.TrimSuffix(function, "Var")
+				if len(arguments) > 0 {
+					arguments = arguments[1:]
+				}
+			} else if function == "TextVar" && len(arguments) > 0 {
+				arguments = arguments[1:]
+			}
+			if !flagFunctionList[function] || len(arguments) < 2 {
+				return true
+			}
+
+			flagName, ok := stringLiteral(arguments[0])
+			if !ok || seen[flagName] {
+				return true
+			}
+			value, usage := "", ""
+			if function == "Func" {
+				usage, _ = stringLiteral(arguments[1])
+			} else {
+				if len(arguments) < 3 {
+					return true
+				}
+				var buffer bytes.Buffer
+				printer.Fprint(&buffer, fset, resolveValue(arguments[1]))
+				value = buffer.String()
+				usage, _ = stringLiteral(arguments[2])
+			}
+			if usage == "\x00" {
+				// Hidden (see kilt.PrintDefaults)
+				return true
+			}
+			seen[flagName] = true
+			flagList = append(flagList, _flag{name: flagName, value: value, usage: usage})
+			return true
+		})
+	}
+	return flagList
+}
+
+// resolveValue follows an identifier to the literal it was declared with
+// (e.g. a constant), so that a default value is shown as "80", rather than "punchCardWidth".
+func resolveValue(node ast.Expr) ast.Expr {
+	ident, ok := node.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return node
+	}
+	spec, ok := ident.Obj.Decl.(*ast.ValueSpec)
+	if !ok {
+		return node
+	}
+	for index, name := range spec.Names {
+		if name.Name == ident.Name && index < len(spec.Values) {
+			if literal, ok := spec.Values[index].(*ast.BasicLit); ok {
+				return literal
+			}
+		}
+	}
+	return node
+}
+
+func stringLiteral(node ast.Expr) (string, bool) {
+	literal, ok := node.(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(literal.Value)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// escapeRoff escapes text for roff, including a leading control character.
+func escapeRoff(text string) string {
+	text = strings.Replace(text, `\`, `\e`, -1)
+	text = strings.Replace(text, "-", `\-`, -1)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// isManHeading reports whether a one-line paragraph of the package
+// documentation is a heading, the same way headifySynopsis does.
+func isManHeading(line string) bool {
+	detect := RenderStyle.SynopsisHeading
+	if detect == nil {
+		return false
+	}
+	line = strings.TrimSpace(line)
+	return detect.FindString(line) == line && line != ""
+}
+
+// renderManTo renders the documentation of a command as a man page
+func renderManTo(writer io.Writer, document *_document) {
+	name := document.Name
+	synopsis := document.pkg.Synopsis(document.pkg.Doc)
+
+	fmt.Fprintf(writer, ".TH %s 1\n", strings.ToUpper(escapeRoff(name)))
+
+	fmt.Fprintf(writer, ".SH NAME\n")
+	fmt.Fprintf(writer, "%s \\- %s\n", escapeRoff(name), escapeRoff(synopsis))
+
+	// Usage lines are taken from shell examples in the documentation ("$ name ...")
+	fmt.Fprintf(writer, ".SH SYNOPSIS\n")
+	var usageList []string
+	for _, block := range blocks(filterText(document.pkg.Doc)) {
+		if block.op != opPre {
+			continue
+		}
+		for _, line := range block.lines {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "$ "+name) {
+				usageList = append(usageList, strings.TrimPrefix(line, "$ "))
+			}
+		}
+	}
+	if len(usageList) == 0 {
+		usageList = append(usageList, name+" [options]")
+	}
+	for index, usage := range usageList {
+		if index > 0 {
+			fmt.Fprintf(writer, ".br\n")
+		}
+		fields := strings.SplitN(usage, " ", 2)
+		fmt.Fprintf(writer, ".B %s\n", escapeRoff(fields[0]))
+		if len(fields) > 1 {
+			fmt.Fprintf(writer, "%s\n", escapeRoff(fields[1]))
+		}
+	}
+
+	fmt.Fprintf(writer, ".SH DESCRIPTION\n")
+	for _, block := range blocks(filterText(document.pkg.Doc)) {
+		switch block.op {
+		case opHead:
+			fmt.Fprintf(writer, ".SS %s\n", escapeRoff(block.lines[0]))
+		case opPara:
+			if len(block.lines) == 1 && isManHeading(block.lines[0]) {
+				fmt.Fprintf(writer, ".SS %s\n", escapeRoff(strings.TrimSpace(block.lines[0])))
+				continue
+			}
+			fmt.Fprintf(writer, ".PP\n")
+			for _, line := range block.lines {
+				fmt.Fprintf(writer, "%s\n", escapeRoff(strings.TrimSpace(line)))
+			}
+		case opPre:
+			fmt.Fprintf(writer, ".PP\n.RS 4\n.nf\n")
+			for _, line := range block.lines {
+				fmt.Fprintf(writer, "%s\n", escapeRoff(strings.TrimRight(line, " \t\n")))
+			}
+			fmt.Fprintf(writer, ".fi\n.RE\n")
+		}
+	}
+
+	if len(document.flags) > 0 {
+		fmt.Fprintf(writer, ".SH OPTIONS\n")
+		for _, entry := range document.flags {
+			fmt.Fprintf(writer, ".TP\n")
+			if entry.value == "" {
+				fmt.Fprintf(writer, "\\fB\\-%s\\fR\n", escapeRoff(entry.name))
+			} else {
+				fmt.Fprintf(writer, "\\fB\\-%s\\fR=%s\n", escapeRoff(entry.name), escapeRoff(entry.value))
+			}
+			fmt.Fprintf(writer, "%s\n", escapeRoff(entry.usage))
+		}
+	}
+}
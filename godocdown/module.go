@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/build"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A requirement of a module (of its go.mod)
+type _require struct {
+	path     string
+	version  string
+	indirect bool // Only required by another requirement ("// indirect")
+}
+
+// The go.mod of a module, as much of it as godocdown uses
+type _module struct {
+	path        string
+	goVersion   string // The go directive, the minimum version of Go
+	requireList []_require
+
+	// The directory of the go.mod (in fsys), the way there from the
+	// directory of the package, and the way back (both slash-separated)
+	fsys        fs.FS
+	directory   string
+	relative    string
+	packagePath string
+}
+
+// readModule parses the go.mod in directory of fsys, or returns nil if there is none.
+func readModule(fsys fs.FS, directory string) *_module {
+	content, err := readFS(fsys, directory, "go.mod")
+	if err != nil {
+		return nil
+	}
+	module := &_module{
+		fsys:        fsys,
+		directory:   directory,
+		relative:    ".",
+		packagePath: ".",
+	}
+	require := func(fields []string, comment string) {
+		comment = strings.TrimSpace(comment)
+		module.requireList = append(module.requireList, _require{
+			path:     strings.Trim(fields[0], "\"`"),
+			version:  fields[1],
+			indirect: comment == "indirect" || strings.HasPrefix(comment, "indirect;"),
+		})
+	}
+
+	inRequire := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line, comment := scanner.Text(), ""
+		if index := strings.Index(line, "//"); index >= 0 {
+			line, comment = line[:index], line[index+len("//"):]
+		}
+		fields := strings.Fields(line)
+		if inRequire {
+			if len(fields) == 1 && fields[0] == ")" {
+				inRequire = false
+			} else if len(fields) >= 2 {
+				require(fields, comment)
+			}
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			module.path = strings.Trim(fields[1], "\"`")
+		case "go":
+			module.goVersion = fields[1]
+		case "require":
+			if fields[1] == "(" {
+				inRequire = true
+			} else if len(fields) >= 3 {
+				require(fields[1:], comment)
+			}
+		}
+	}
+	return module
+}
+
+// importPath returns the import path of the package of the module (see
+// findModule), or "" if the module has no path
+func (self *_module) importPath() string {
+	if self == nil || self.path == "" {
+		return ""
+	}
+	return path.Join(self.path, self.packagePath)
+}
+
+// findModule returns the module of the package of document: the nearest
+// go.mod at or above its directory, first in the filesystem it was read
+// from (a zip of a module, say), then on disk (unless it was read from a git
+// revision). It returns nil if there is none.
+func findModule(document *_document) *_module {
+	relative, packagePath := ".", "."
+	found := func(module *_module) *_module {
+		module.relative, module.packagePath = relative, packagePath
+		return module
+	}
+
+	if document.fsys != nil {
+		for directory := document.directory; ; directory = path.Dir(directory) {
+			if module := readModule(document.fsys, directory); module != nil {
+				return found(module)
+			}
+			if directory == "." || directory == "/" {
+				break
+			}
+			relative = path.Join(relative, "..")
+			packagePath = path.Join(path.Base(directory), packagePath)
+		}
+	}
+
+	if _, ok := document.fsys.(*_gitTree); ok {
+		// The tree is the whole repository, and what's on disk is the
+		// working tree, not the revision
+		return nil
+	}
+	if info, err := os.Stat(document.buildPkg.Dir); err != nil || !info.IsDir() {
+		return nil
+	}
+	directory, err := filepath.Abs(document.buildPkg.Dir)
+	if err != nil {
+		return nil
+	}
+	relative, packagePath = ".", "."
+	for {
+		if module := readModule(os.DirFS(directory), "."); module != nil {
+			return found(module)
+		}
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return nil
+		}
+		relative = path.Join(relative, "..")
+		packagePath = path.Join(filepath.Base(directory), packagePath)
+		directory = parent
+	}
+}
+
+// resolveImportPath returns the import path of the package of document: its
+// ImportPath, unless that is a directory (as outside of GOPATH), in which
+// case the one of its module. It returns "" if there is neither.
+func resolveImportPath(document *_document, module *_module) string {
+	importPath := document.ImportPath
+	if importPath == "" || build.IsLocalImport(importPath) || path.IsAbs(importPath) {
+		importPath = module.importPath()
+	}
+	if build.IsLocalImport(importPath) || path.IsAbs(importPath) {
+		return ""
+	}
+	return importPath
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFile writes content to filename atomically (to a temporary file in the
+// same directory, renamed into place), so that an interrupted write never
+// leaves a truncated file behind. An existing file keeps its mode (and a
+// symbolic link keeps pointing at the file it points to). The parent directory
+// is created if missing, if createParent is true.
+func writeFile(filename string, content []byte, createParent bool) error {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+
+	mode := os.FileMode(0644)
+	info, err := os.Stat(filename)
+	exists := err == nil
+	if exists {
+		if info.IsDir() {
+			return fmt.Errorf("Could not write \"%s\": is a directory", filename)
+		}
+		mode = info.Mode().Perm()
+	}
+
+	parent := filepath.Dir(filename)
+	if createParent {
+		err := os.MkdirAll(parent, 0755)
+		if err != nil {
+			return fmt.Errorf("Could not write \"%s\": %v", filename, err)
+		}
+	} else if _, err := os.Stat(parent); os.IsNotExist(err) {
+		return fmt.Errorf("Could not write \"%s\": directory \"%s\" does not exist (use -mkdir to create it)", filename, parent)
+	}
+
+	err = kilt.WriteAtomicFile(filename, bytes.NewReader(content), mode)
+	if err != nil {
+		return fmt.Errorf("Could not write \"%s\": %v", filename, err)
+	}
+	if exists {
+		// The temporary file was created subject to the umask
+		err = os.Chmod(filename, mode)
+		if err != nil {
+			return fmt.Errorf("Could not write \"%s\": %v", filename, err)
+		}
+	}
+	return nil
+}
+
+// fileLink returns the link to a file of the package (at relative, slash-separated,
+// to its directory) from where the documentation is written. On stdout, that is
+// taken to be the directory of the package, as for a README.
+func (self *_document) fileLink(relative string) string {
+	if self.outputDirectory == "" {
+		return relative
+	}
+	from, err := filepath.Abs(self.outputDirectory)
+	if err != nil {
+		return relative
+	}
+	to, err := filepath.Abs(filepath.Join(self.buildPkg.Dir, filepath.FromSlash(relative)))
+	if err != nil {
+		return relative
+	}
+	link, err := filepath.Rel(from, to)
+	if err != nil {
+		return relative
+	}
+	return filepath.ToSlash(link)
+}
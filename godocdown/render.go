@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/doc"
 	"io"
+	"strings"
 )
 
 func renderConstantSectionTo(writer io.Writer, list []*doc.Value) {
@@ -30,16 +31,20 @@ func renderFunctionSectionTo(writer io.Writer, list []*doc.Func, inTypeSection b
 		if entry.Recv != "" {
 			receiver = fmt.Sprintf("(%s) ", entry.Recv)
 		}
-		fmt.Fprintf(writer, "%s func %s%s\n\n%s\n%s\n", header, receiver, entry.Name, indentCode(sourceOfNode(entry.Decl)), formatIndent(filterText(entry.Doc)))
+		fmt.Fprintf(writer, "%s\n\n%s\n%s\n", formatHeading(header, fmt.Sprintf("func %s%s%s", receiver, entry.Name, funcTypeParams(entry))), indentCode(sourceOfNode(entry.Decl)), formatIndent(filterText(entry.Doc)))
+		renderExamplesTo(writer, entry.Examples)
 	}
 }
 
-func renderTypeSectionTo(writer io.Writer, list []*doc.Type) {
+func renderTypeSectionTo(writer io.Writer, list []*doc.Type, document *_document) {
 
 	header := RenderStyle.TypeHeader
 
 	for _, entry := range list {
-		fmt.Fprintf(writer, "%s type %s\n\n%s\n\n%s\n", header, entry.Name, indentCode(sourceOfNode(entry.Decl)), formatIndent(filterText(entry.Doc)))
+		fmt.Fprintf(writer, "%s\n\n%s\n\n%s\n", formatHeading(header, "type "+entry.Name+typeTypeParams(entry)), indentCode(sourceOfNode(entry.Decl)), formatIndent(filterText(entry.Doc)))
+		renderFieldsTo(writer, entry)
+		renderImplementsTo(writer, entry, document)
+		renderExamplesTo(writer, entry.Examples)
 		renderConstantSectionTo(writer, entry.Consts)
 		renderVariableSectionTo(writer, entry.Vars)
 		renderFunctionSectionTo(writer, entry.Funcs, true)
@@ -47,26 +52,53 @@ func renderTypeSectionTo(writer io.Writer, list []*doc.Type) {
 	}
 }
 
-func renderHeaderTo(writer io.Writer, document *_document) {
-	fmt.Fprintf(writer, "# %s\n--\n", document.Name)
+func renderImplementsTo(writer io.Writer, entry *doc.Type, document *_document) {
+	if !RenderStyle.IncludeImplements || document.implements == nil {
+		return
+	}
+	if list := document.implements.implements[entry.Name]; len(list) > 0 {
+		fmt.Fprintf(writer, "%s\n", renderer().Paragraph("Implements: "+formatImplementsList(list)))
+	}
+	if list := document.implements.implementedBy[entry.Name]; len(list) > 0 {
+		fmt.Fprintf(writer, "%s\n", renderer().Paragraph("Implemented by: "+formatImplementsList(list)))
+	}
+}
+
+func formatImplementsList(list []_implementsEntry) string {
+	render := renderer()
+	textList := make([]string, 0, len(list))
+	for _, entry := range list {
+		text := render.CodeSpan(entry.name)
+		if entry.as != "" {
+			text += " (as " + render.CodeSpan(entry.as) + ")"
+		}
+		textList = append(textList, text)
+	}
+	return strings.Join(textList, ", ")
+}
 
+func renderHeaderTo(writer io.Writer, document *_document) {
+	importPath := ""
 	if !document.IsCommand {
 		// Import
 		if RenderStyle.IncludeImport {
-			if document.ImportPath != "" {
-				fmt.Fprintf(writer, spacer(4)+"import \"%s\"\n\n", document.ImportPath)
-			}
+			importPath = document.ImportPath
 		}
 	}
+	fmt.Fprintf(writer, "%s", renderer().Header(document.Name, importPath))
+	if RenderStyle.IncludeBadges && !document.badgesPlaced {
+		renderBadgesTo(writer, document)
+	}
 }
 
 func renderSynopsisTo(writer io.Writer, document *_document) {
-	fmt.Fprintf(writer, "%s\n", headifySynopsis(formatIndent(filterText(document.pkg.Doc))))
+	fmt.Fprintf(writer, "%s\n", renderer().Synopsis(formatIndent(filterText(document.pkg.Doc))))
+	renderExamplesTo(writer, document.pkg.Examples)
 }
 
 func renderUsageTo(writer io.Writer, document *_document) {
 	// Usage
-	fmt.Fprintf(writer, "%s\n", RenderStyle.UsageHeader)
+	fmt.Fprintf(writer, "%s\n\n", formatHeading(RenderStyle.UsageHeader, "Usage"))
 
 	// Constant Section
 	renderConstantSectionTo(writer, document.pkg.Consts)
@@ -77,12 +109,62 @@ func renderUsageTo(writer io.Writer, document *_document) {
 	// Function Section
 	renderFunctionSectionTo(writer, document.pkg.Funcs, false)
 
+	typeList, constraintList := splitConstraints(document.pkg)
+
 	// Type Section
-	renderTypeSectionTo(writer, document.pkg.Types)
+	renderTypesTo(writer, typeList, document)
+
+	// Constraint Section
+	if len(constraintList) > 0 {
+		fmt.Fprintf(writer, "%s\n\n", formatHeading(RenderStyle.ConstraintHeader, "Constraints"))
+		renderTypesTo(writer, constraintList, document)
+	}
+}
+
+func renderTypesTo(writer io.Writer, list []*doc.Type, document *_document) {
+	if document.split {
+		renderTypeIndexTo(writer, list, document)
+		return
+	}
+	renderTypeSectionTo(writer, list, document)
+}
+
+// renderTypeIndexTo links to the page of each type, rather than rendering
+// the type itself (see -split)
+func renderTypeIndexTo(writer io.Writer, list []*doc.Type, document *_document) {
+	header := RenderStyle.TypeHeader
+
+	for _, entry := range list {
+		link := renderer().Link(fmt.Sprintf("type %s%s", entry.Name, typeTypeParams(entry)), splitTypeFilename(entry))
+		fmt.Fprintf(writer, "%s\n\n", formatHeading(header, link))
+		if synopsis := document.pkg.Synopsis(entry.Doc); synopsis != "" {
+			fmt.Fprintf(writer, "%s\n", formatIndent(filterText(synopsis)))
+		}
+	}
+}
+
+// renderTypePageTo renders the page for a single type (see -split), with
+// its constants, variables, constructors and methods, after links to the
+// index and to the other types
+func renderTypePageTo(writer io.Writer, entry *doc.Type, document *_document) {
+	fmt.Fprintf(writer, "%s", renderer().Header(document.Name+"."+entry.Name, ""))
+	fmt.Fprintf(writer, "%s\n\n", renderer().Link(document.Name, splitIndexFilename()))
+	if len(document.pkg.Types) > 1 {
+		linkList := make([]string, 0, len(document.pkg.Types))
+		for _, other := range document.pkg.Types {
+			if other == entry {
+				linkList = append(linkList, other.Name)
+			} else {
+				linkList = append(linkList, renderer().Link(other.Name, splitTypeFilename(other)))
+			}
+		}
+		fmt.Fprintf(writer, "Types: %s\n\n", strings.Join(linkList, ", "))
+	}
+	renderTypeSectionTo(writer, []*doc.Type{entry}, document)
 }
 
 func renderSignatureTo(writer io.Writer) {
 	if RenderStyle.IncludeSignature {
-		fmt.Fprintf(writer, "\n\n--\n**godocdown** http://github.com/robertkrimen/godocdown\n")
+		fmt.Fprintf(writer, "%s", renderer().Signature())
 	}
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Renderer is the markup of an output format. The traversal of a package
+// (renderUsageTo and friends) decides what is emitted and in what order,
+// a Renderer decides what it looks like.
+//
+// A custom style can embed one of the existing renderers and override
+// only what it needs, e.g.:
+//
+//	type _companyRenderer struct{ _gfmRenderer }
+//
+//	func (_companyRenderer) Heading(level int, text string) string { ... }
+//
+// and be added to rendererMap (and formatList, to be selectable with -format).
+type Renderer interface {
+	// Extension is the filename extension of a page (see -split and -site)
+	Extension() string
+
+	// Header returns the title of a page, followed by an import line
+	// (unless importPath is "")
+	Header(title, importPath string) string
+
+	// Synopsis returns the (formatted) package documentation, with headings detected
+	Synopsis(text string) string
+
+	// Heading returns text as a heading of level, from 1 to 6
+	// (formatHeading has already applied the offset)
+	Heading(level int, text string) string
+
+	// CodeBlock returns source as a code block, labelled with language (if any)
+	CodeBlock(language, source string) string
+
+	// CodeBlockRegexp matches a complete code block, as returned by CodeBlock,
+	// which heading detection should leave alone
+	CodeBlockRegexp() *regexp.Regexp
+
+	// Paragraph returns a paragraph of (already escaped) text
+	Paragraph(text string) string
+
+	// Escape escapes a single word of a paragraph of documentation text,
+	// lineStart is true for the first word of a line, and spans tracks
+	// the `code spans` of the paragraph across words
+	Escape(word string, lineStart bool, spans *_codeSpans) string
+
+	CodeSpan(text string) string
+	Emphasis(text string) string
+
+	// Link returns a link to target (a URL, or a page relative to the current one)
+	Link(text, target string) string
+
+	// List returns a bulleted list, one item per line
+	List(itemList []string) string
+
+	// Badges returns a row of badges (see -badges), each an image linking somewhere
+	Badges(badgeList []_badge) string
+
+	// Table returns a table with a header row, TableCell escapes a cell of it
+	Table(header []string, rowList [][]string) string
+	TableCell(cell string) string
+
+	Signature() string
+}
+
+// rendererMap maps each -format to its Renderer ("plain" is -plain)
+var rendererMap = map[string]Renderer{
+	"markdown": _gfmRenderer{},
+	"plain":    _plainRenderer{},
+	"asciidoc": _asciidocRenderer{},
+	"rst":      _rstRenderer{},
+}
+
+// The output formats that -format knows about. A man page is a different
+// kind of document altogether (see renderManTo), the others share the
+// header, synopsis, and usage structure of the Markdown.
+var formatList = []string{"markdown", "asciidoc", "rst", "man"}
+
+func validFormat(format string) bool {
+	for _, valid := range formatList {
+		if format == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// selectFormat sets RenderStyle.Format, with -plain choosing standard Markdown
+func selectFormat(format string) {
+	if format == "markdown" && *flag_plain {
+		format = "plain"
+	}
+	RenderStyle.Format = format
+}
+
+// renderer returns the Renderer of RenderStyle.Format
+func renderer() Renderer {
+	if renderer, exists := rendererMap[RenderStyle.Format]; exists {
+		return renderer
+	}
+	return rendererMap["markdown"]
+}
+
+// The levels of the headings enclosing the current one (see contiguousLevel)
+var headingStack []int
+
+// resetHeadings starts a new document (see contiguousLevel)
+func resetHeadings() {
+	headingStack = nil
+}
+
+// contiguousLevel returns the level of a heading of level, so that the
+// headings of a document nest without skipping a level (which AsciiDoc
+// warns of, and reStructuredText gets wrong): one below the heading that
+// encloses it (the last of a lower level), if any. With the default
+// levels, the title (1), a heading of the synopsis (3), the usage (2),
+// and a function (4) are 1, 2 (the level of the usage), 2, and 3.
+func contiguousLevel(level int) int {
+	for len(headingStack) > 0 && headingStack[len(headingStack)-1] >= level {
+		headingStack = headingStack[:len(headingStack)-1]
+	}
+	headingStack = append(headingStack, level)
+	level = RenderStyle.HeadingOffset + len(headingStack)
+	if level > 6 {
+		level = 6
+	}
+	return level
+}
+
+// _gfmRenderer renders Github Flavored Markdown (the default)
+type _gfmRenderer struct{}
+
+func (_gfmRenderer) Extension() string {
+	return ".md"
+}
+
+func (_gfmRenderer) Header(title, importPath string) string {
+	header := formatHeading(RenderStyle.TitleHeader, title) + "\n--\n"
+	if importPath != "" {
+		header += fmt.Sprintf(spacer(4)+"import \"%s\"\n\n", importPath)
+	}
+	return header
+}
+
+func (_gfmRenderer) Synopsis(text string) string {
+	return headifySynopsis(text)
+}
+
+func (_gfmRenderer) Heading(level int, text string) string {
+	if RenderStyle.HeadingSetext && level <= 2 {
+		underline := "="
+		if level == 2 {
+			underline = "-"
+		}
+		width := utf8.RuneCountInString(text)
+		if width < 3 {
+			width = 3
+		}
+		return fmt.Sprintf("%s\n%s", text, strings.Repeat(underline, width))
+	}
+	return fmt.Sprintf("%s %s", strings.Repeat("#", level), text)
+}
+
+// CodeBlock fences source with more backticks than any run of them in it
+func (_gfmRenderer) CodeBlock(language, source string) string {
+	fence := codeFence(source)
+	return fmt.Sprintf("%s%s\n%s\n%s", fence, language, source, fence)
+}
+
+func (_gfmRenderer) CodeBlockRegexp() *regexp.Regexp {
+	return fence_Regexp
+}
+
+func (_gfmRenderer) Paragraph(text string) string {
+	return text + "\n"
+}
+
+func (_gfmRenderer) Escape(word string, lineStart bool, spans *_codeSpans) string {
+	return escapeMarkdown(word, lineStart, spans, true)
+}
+
+func (_gfmRenderer) CodeSpan(text string) string {
+	if text == "" {
+		return ""
+	}
+	if strings.Contains(text, "`") {
+		return "`` " + text + " ``"
+	}
+	return "`" + text + "`"
+}
+
+func (_gfmRenderer) Emphasis(text string) string {
+	return "*" + text + "*"
+}
+
+func (_gfmRenderer) Link(text, target string) string {
+	return fmt.Sprintf("[%s](%s)", text, target)
+}
+
+func (_gfmRenderer) Badges(badgeList []_badge) string {
+	textList := make([]string, 0, len(badgeList))
+	for _, badge := range badgeList {
+		textList = append(textList, fmt.Sprintf("[![%s](%s)](%s)", badge.alt, badge.image, badge.link))
+	}
+	return strings.Join(textList, " ") + "\n"
+}
+
+func (_gfmRenderer) List(itemList []string) string {
+	var buffer bytes.Buffer
+	for _, item := range itemList {
+		fmt.Fprintf(&buffer, "* %s\n", item)
+	}
+	return buffer.String()
+}
+
+func (_gfmRenderer) Table(header []string, rowList [][]string) string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "| %s |\n", strings.Join(header, " | "))
+	for _, cell := range header {
+		fmt.Fprintf(&buffer, "|%s", strings.Repeat("-", utf8.RuneCountInString(cell)+2))
+	}
+	fmt.Fprintf(&buffer, "|\n")
+	for _, row := range rowList {
+		fmt.Fprintf(&buffer, "| %s |\n", strings.Join(row, " | "))
+	}
+	return buffer.String()
+}
+
+func (_gfmRenderer) TableCell(cell string) string {
+	return strings.Replace(cell, "|", `\|`, -1)
+}
+
+func (_gfmRenderer) Signature() string {
+	return "\n\n--\n**godocdown** http://github.com/robertkrimen/godocdown\n"
+}
+
+// _plainRenderer renders standard Markdown, which has no fenced code blocks
+// and no tables
+type _plainRenderer struct {
+	_gfmRenderer
+}
+
+func (_plainRenderer) CodeBlock(language, source string) string {
+	return indent(source+"\n", spacer(4))
+}
+
+func (_plainRenderer) Escape(word string, lineStart bool, spans *_codeSpans) string {
+	return escapeMarkdown(word, lineStart, spans, false)
+}
+
+// Table renders each row as a list item instead: the first cell, the cells
+// in between, and the last cell (the description) after a colon.
+func (self _plainRenderer) Table(header []string, rowList [][]string) string {
+	itemList := make([]string, 0, len(rowList))
+	for _, row := range rowList {
+		if len(row) == 0 {
+			continue
+		}
+		item := row[0]
+		for index := 1; index < len(row)-1; index++ {
+			if row[index] != "" {
+				item += " " + row[index]
+			}
+		}
+		if last := row[len(row)-1]; len(row) > 1 && last != "" {
+			item += ": " + last
+		}
+		itemList = append(itemList, item)
+	}
+	return self.List(itemList)
+}
+
+func (_plainRenderer) TableCell(cell string) string {
+	return cell
+}
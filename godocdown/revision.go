@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+)
+
+// git runs git in directory, returning its output (or its complaint as the error).
+func git(directory string, arguments ...string) ([]byte, error) {
+	command := kilt.ExecCommand("git", arguments...)
+	command.Dir = directory
+	output, err := command.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git %s: %s", arguments[0], bytes.TrimSpace(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("git %s: %v", arguments[0], err)
+	}
+	return output, nil
+}
+
+// loadRevision loads the package target as of a git revision (a commit,
+// tag, or branch) of its repository, without checking the revision out.
+// Its go.mod and LICENSE are read from the revision too. The package need
+// not be in the working tree (any more), only its repository.
+func loadRevision(target, revision string) (*_document, error) {
+	buildPkg, err := buildImport(target)
+	if err != nil || buildPkg.Dir == "" {
+		// Gone from the working tree, the import path is that of the module
+		// of the revision (if any)
+		absolute, absoluteErr := filepath.Abs(target)
+		if absoluteErr != nil {
+			return nil, fmt.Errorf("Could not find package \"%s\"", target)
+		}
+		buildPkg = &build.Package{Dir: absolute}
+	}
+
+	// git runs in the nearest directory (on the way to the package) that exists
+	existing := buildPkg.Dir
+	for {
+		if info, err := os.Stat(existing); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	tree, directory, err := newGitTree(existing, revision)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read \"%s\" at %s: %v", target, revision, err)
+	}
+	rest, err := filepath.Rel(existing, buildPkg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	directory = path.Join(directory, filepath.ToSlash(rest))
+	if info, err := fs.Stat(tree, directory); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("Could not find package \"%s\" at %s", target, revision)
+	}
+
+	document, err := loadDocumentFS(tree, directory, buildPkg)
+	if document != nil && buildPkg.ImportPath == "" {
+		document.ImportPath = resolveImportPath(document, findModule(document))
+	}
+	return document, err
+}
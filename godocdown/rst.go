@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// The underline of a reStructuredText heading, by level. The levels of
+// a document are contiguous (see contiguousLevel), so the underlines are
+// given out in the order they are first used, which is how docutils ranks them.
+var rstUnderlineList = []string{"=", "-", "~", "^", "\"", "'"}
+
+// _rstRenderer renders reStructuredText (for Sphinx)
+type _rstRenderer struct{}
+
+func (_rstRenderer) Extension() string {
+	return ".rst"
+}
+
+func (self _rstRenderer) Header(title, importPath string) string {
+	resetHeadings()
+	header := formatHeading(RenderStyle.TitleHeader, title) + "\n"
+	if importPath != "" {
+		code := self.CodeBlock("go", fmt.Sprintf("import \"%s\"", importPath))
+		header += fmt.Sprintf("\n%s\n\n", strings.TrimRight(code, "\n"))
+	}
+	return header
+}
+
+func (_rstRenderer) Synopsis(text string) string {
+	return headifySynopsis(text)
+}
+
+func (_rstRenderer) Heading(level int, text string) string {
+	level = contiguousLevel(level)
+	width := utf8.RuneCountInString(text)
+	if width < 3 {
+		width = 3
+	}
+	return fmt.Sprintf("%s\n%s", text, strings.Repeat(rstUnderlineList[level-1], width))
+}
+
+func (_rstRenderer) CodeBlock(language, source string) string {
+	directive := "::"
+	if language != "" {
+		directive = ".. code-block:: " + language
+	}
+	return fmt.Sprintf("%s\n\n%s", directive, indent(source+"\n", spacer(4)))
+}
+
+// CodeBlockRegexp matches fenced code blocks (which can come from
+// -markdown-comments); reStructuredText code is indented, so it can't
+// be mistaken for a heading
+func (_rstRenderer) CodeBlockRegexp() *regexp.Regexp {
+	return fence_Regexp
+}
+
+func (_rstRenderer) Paragraph(text string) string {
+	return text + "\n"
+}
+
+func (_rstRenderer) Escape(word string, lineStart bool, spans *_codeSpans) string {
+	return escapeRST(word, lineStart, spans)
+}
+
+func (_rstRenderer) CodeSpan(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "``" + text + "``"
+}
+
+func (_rstRenderer) Emphasis(text string) string {
+	return "*" + text + "*"
+}
+
+func (_rstRenderer) Link(text, target string) string {
+	if strings.HasSuffix(target, ".rst") && !strings.Contains(target, "://") {
+		// A document reference (for Sphinx), without the extension
+		return fmt.Sprintf(":doc:`%s <%s>`", text, strings.TrimSuffix(target, ".rst"))
+	}
+	return fmt.Sprintf("`%s <%s>`__", text, target)
+}
+
+// Badges returns an image directive per badge, as an inline image
+// would need a substitution definition apiece
+func (_rstRenderer) Badges(badgeList []_badge) string {
+	textList := make([]string, 0, len(badgeList))
+	for _, badge := range badgeList {
+		textList = append(textList, fmt.Sprintf(".. image:: %s\n   :alt: %s\n   :target: %s\n", badge.image, badge.alt, badge.link))
+	}
+	return strings.Join(textList, "\n")
+}
+
+func (_rstRenderer) List(itemList []string) string {
+	var buffer bytes.Buffer
+	for _, item := range itemList {
+		fmt.Fprintf(&buffer, "* %s\n", item)
+	}
+	return buffer.String()
+}
+
+func (_rstRenderer) Table(header []string, rowList [][]string) string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, ".. list-table::\n   :header-rows: 1\n\n")
+	for _, row := range append([][]string{header}, rowList...) {
+		for index, cell := range row {
+			bullet := "     -"
+			if index == 0 {
+				bullet = "   * -"
+			}
+			if cell == "" {
+				fmt.Fprintf(&buffer, "%s\n", bullet)
+			} else {
+				fmt.Fprintf(&buffer, "%s %s\n", bullet, cell)
+			}
+		}
+	}
+	return buffer.String()
+}
+
+// TableCell leaves the cell alone, the cells of a list-table are list items
+func (_rstRenderer) TableCell(cell string) string {
+	return cell
+}
+
+func (_rstRenderer) Signature() string {
+	return "\n\n----\n\n**godocdown** http://github.com/robertkrimen/godocdown\n"
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A package of a site (see -site)
+type _sitePackage struct {
+	path       string // The directory, relative to the root (slash-separated, "." for the root)
+	importPath string
+	name       string
+	synopsis   string
+	imports    []string
+	body       string
+}
+
+// The page of the package, relative to the site directory
+func (self *_sitePackage) page() string {
+	return path.Join(self.path, siteIndexFilename())
+}
+
+// relativeLink returns a link from one page of the site to another.
+func relativeLink(from, to string) string {
+	link, err := filepath.Rel(filepath.Dir(filepath.FromSlash(from)), filepath.FromSlash(to))
+	if err != nil {
+		return to
+	}
+	link = filepath.ToSlash(link)
+	if RenderStyle.SiteFormat == "hugo" {
+		// Hugo doesn't resolve links to files, so link to the section instead
+		link = strings.TrimSuffix(link, siteIndexFilename())
+		if link == "" {
+			link = "./"
+		}
+	}
+	return link
+}
+
+// readModulePath returns the module path declared by the go.mod in directory, if any.
+func readModulePath(directory string) string {
+	if module := readModule(os.DirFS(directory), "."); module != nil {
+		return module.path
+	}
+	return ""
+}
+
+// siteImportPath returns the import path of the package in directory (at
+// relative of the root of the site): that of its .godocdown.import, if any,
+// or else of the module (if modulePath is not ""), or else of GOPATH
+func siteImportPath(directory, relative, modulePath string) string {
+	if read, err := ioutil.ReadFile(filepath.Join(directory, ".godocdown.import")); err == nil {
+		return strings.TrimSpace(strings.Split(string(read), "\n")[0])
+	}
+	if modulePath != "" {
+		return path.Join(modulePath, relative)
+	}
+	importPath, _ := guessImportPath(directory)
+	return importPath
+}
+
+// findPackageDirectories returns every directory under root (including root)
+// that might contain a package, skipping hidden (".") and ignored ("_")
+// directories, testdata, vendor, and the site directory itself (skip, if not "").
+func findPackageDirectories(root, skip string) ([]string, error) {
+	if skip != "" {
+		skip, _ = filepath.Abs(skip)
+	}
+	var directoryList []string
+	err := filepath.Walk(root, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if walkPath != root && (name[0] == '.' || name[0] == '_' || name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+		if absolute, _ := filepath.Abs(walkPath); skip != "" && absolute == skip {
+			return filepath.SkipDir
+		}
+		directoryList = append(directoryList, walkPath)
+		return nil
+	})
+	return directoryList, err
+}
+
+// loadSite documents every package under root, reusing the documentation
+// of every package that is unchanged since it was cached.
+func loadSite(root, directory string, cache *_cache) ([]*_sitePackage, error) {
+	directoryList, err := findPackageDirectories(root, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	modulePath := readModulePath(root)
+	markdownComments := RenderStyle.MarkdownComments
+	defer func() {
+		RenderStyle.MarkdownComments = markdownComments
+		RenderStyle.SiteLinks = nil
+	}()
+
+	// The page of every (would be) package, by import path, for SiteLinks
+	relativeList := make([]string, len(directoryList))
+	pageOf := map[string]string{}
+	var pageList []string
+	for index, packageDirectory := range directoryList {
+		relative, err := filepath.Rel(root, packageDirectory)
+		if err != nil {
+			return nil, err
+		}
+		relativeList[index] = filepath.ToSlash(relative)
+		if importPath := siteImportPath(packageDirectory, relativeList[index], modulePath); importPath != "" {
+			page := path.Join(relativeList[index], siteIndexFilename())
+			pageOf[importPath] = page
+			pageList = append(pageList, importPath+" "+page)
+		}
+	}
+	sort.Strings(pageList)
+	pages := strings.Join(pageList, "\n")
+
+	var packageList []*_sitePackage
+	for index, packageDirectory := range directoryList {
+		relative := relativeList[index]
+
+		RenderStyle.MarkdownComments = markdownComments
+		RenderStyle.SiteLinks = nil
+		// Any page might be linked to (see SiteLinks), so a package is
+		// only fresh if the pages are the same
+		entry, fresh := cache.lookup(packageDirectory, modulePath, relative, pages)
+		if fresh {
+			if cached := entry.Package; cached != nil {
+				packageList = append(packageList, &_sitePackage{
+					path:       relative,
+					importPath: cached.ImportPath,
+					name:       cached.Name,
+					synopsis:   cached.Synopsis,
+					imports:    cached.Imports,
+					body:       cached.Body,
+				})
+			}
+			continue
+		}
+
+		document, err := loadDocument(packageDirectory)
+		if err != nil {
+			return nil, err
+		}
+		if document == nil {
+			continue
+		}
+
+		if importPath := siteImportPath(packageDirectory, relative, modulePath); importPath != "" {
+			document.ImportPath = importPath
+		}
+		importPath := document.ImportPath
+
+		RenderStyle.MarkdownComments = markdownComments || document.MarkdownComments
+		RenderStyle.SiteLinks = map[string]string{}
+		page := path.Join(relative, siteIndexFilename())
+		document.outputDirectory = filepath.Join(directory, filepath.FromSlash(path.Dir(page)))
+		for _, imported := range document.pkg.Imports {
+			if importedPage, exists := pageOf[imported]; exists && imported != importPath {
+				RenderStyle.SiteLinks[path.Base(imported)] = relativeLink(page, importedPage)
+			}
+		}
+		body, err := emitDocument(document, loadTemplate(document))
+		if err != nil {
+			return nil, fmt.Errorf("Error running template: %v", err)
+		}
+
+		sitePackage := &_sitePackage{
+			path:       relative,
+			importPath: importPath,
+			name:       document.Name,
+			synopsis:   document.pkg.Synopsis(document.pkg.Doc),
+			imports:    document.pkg.Imports,
+			body:       body,
+		}
+		packageList = append(packageList, sitePackage)
+		if entry != nil {
+			entry.Package = &_cachePackage{
+				ImportPath: sitePackage.importPath,
+				Name:       sitePackage.name,
+				Synopsis:   sitePackage.synopsis,
+				Imports:    sitePackage.imports,
+				Body:       sitePackage.body,
+			}
+		}
+	}
+
+	sort.Sort(_sitePackageSort(packageList))
+	return packageList, nil
+}
+
+type _sitePackageSort []*_sitePackage
+
+func (self _sitePackageSort) Len() int           { return len(self) }
+func (self _sitePackageSort) Swap(i, j int)      { self[i], self[j] = self[j], self[i] }
+func (self _sitePackageSort) Less(i, j int) bool { return self[i].path < self[j].path }
+
+// parentOf returns the nearest package above the given one (or nil).
+func parentOf(packageList []*_sitePackage, target *_sitePackage) *_sitePackage {
+	if target.path == "." {
+		return nil
+	}
+	for parent := path.Dir(target.path); ; parent = path.Dir(parent) {
+		for _, entry := range packageList {
+			if entry.path == parent {
+				return entry
+			}
+		}
+		if parent == "." || parent == "/" {
+			return nil
+		}
+	}
+}
+
+// renderSiteNavigationTo renders the links from one package of the site
+// to its parent, children, and the packages it imports or is imported by.
+func renderSiteNavigationTo(buffer *bytes.Buffer, packageList []*_sitePackage, target *_sitePackage) {
+	page := target.page()
+	byImportPath := map[string]*_sitePackage{}
+	for _, entry := range packageList {
+		byImportPath[entry.importPath] = entry
+	}
+
+	list := func(heading string, entryList []*_sitePackage) {
+		if len(entryList) == 0 {
+			return
+		}
+		render := renderer()
+		itemList := make([]string, 0, len(entryList))
+		for _, entry := range entryList {
+			item := render.Link(entry.path, relativeLink(page, entry.page()))
+			if entry.synopsis != "" {
+				item += " - " + escapeInline(entry.synopsis)
+			}
+			itemList = append(itemList, item)
+		}
+		fmt.Fprintf(buffer, "\n\n%s\n\n%s", formatHeading(RenderStyle.UsageHeader, heading), strings.TrimRight(render.List(itemList), "\n"))
+	}
+
+	var childList, importList, importedByList []*_sitePackage
+	for _, entry := range packageList {
+		if entry != target && (target.path == "." || parentOf(packageList, entry) == target) {
+			// The index lists every package
+			childList = append(childList, entry)
+		}
+		for _, importPath := range entry.imports {
+			if importPath == target.importPath && entry != target {
+				importedByList = append(importedByList, entry)
+			}
+		}
+	}
+	for _, importPath := range target.imports {
+		if entry, exists := byImportPath[importPath]; exists && entry != target {
+			importList = append(importList, entry)
+		}
+	}
+
+	if target.path == "." {
+		list("Packages", childList)
+	} else {
+		list("Subpackages", childList)
+	}
+	list("Imports", importList)
+	list("Imported By", importedByList)
+}
+
+// emitSite writes a page for every package under root into directory,
+// with the root package (or just a list of packages) as the index.
+// Only the pages that changed are written (see -force).
+func emitSite(root, directory string) error {
+	cache := loadCache(directory)
+	packageList, err := loadSite(root, directory, cache)
+	if err != nil {
+		return err
+	}
+	if len(packageList) == 0 || packageList[0].path != "." {
+		// There is no package at the root, so make an index
+		name := filepath.Base(root)
+		if absolute, err := filepath.Abs(root); err == nil {
+			name = filepath.Base(absolute)
+		}
+		if modulePath := readModulePath(root); modulePath != "" {
+			name = modulePath
+		}
+		index := &_sitePackage{
+			path: ".",
+			name: name,
+			body: strings.TrimSpace(renderer().Header(name, "")),
+		}
+		packageList = append([]*_sitePackage{index}, packageList...)
+	}
+
+	for weight, entry := range packageList {
+		var buffer bytes.Buffer
+		renderFrontMatterTo(&buffer, entry, weight+1)
+		if parent := parentOf(packageList, entry); parent != nil {
+			fmt.Fprintf(&buffer, "%s\n\n", renderer().Link(parent.name, relativeLink(entry.page(), parent.page())))
+		}
+		buffer.WriteString(entry.body)
+		renderSiteNavigationTo(&buffer, packageList, entry)
+
+		filename := filepath.Join(directory, filepath.FromSlash(entry.page()))
+		err := cache.write(filename, []byte(strings.TrimSpace(buffer.String())+"\n"), true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if RenderStyle.SiteFormat == "mkdocs" {
+		var buffer bytes.Buffer
+		renderMkDocsNavigationTo(&buffer, packageList)
+		err := cache.write(filepath.Join(directory, mkdocsNavigationFilename), buffer.Bytes(), true)
+		if err != nil {
+			return err
+		}
+	}
+	return cache.save()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// The static site generators that -site-format knows about
+var siteFormatList = []string{"", "hugo", "docusaurus", "mkdocs"}
+
+// The navigation for MkDocs, which can be pulled into mkdocs.yml with:
+//
+//	INHERIT: docs/mkdocs.nav.yml
+const mkdocsNavigationFilename = "mkdocs.nav.yml"
+
+// siteIndexFilename is the filename of the page of each package of a site.
+func siteIndexFilename() string {
+	if RenderStyle.SiteFormat == "hugo" {
+		// Every package is a section (a branch bundle) in Hugo
+		return "_index" + renderer().Extension()
+	}
+	return splitIndexFilename()
+}
+
+// renderFrontMatterTo renders YAML front matter for site generators that want it.
+func renderFrontMatterTo(buffer *bytes.Buffer, entry *_sitePackage, weight int) {
+	weightKey := ""
+	switch RenderStyle.SiteFormat {
+	case "hugo":
+		weightKey = "weight"
+	case "docusaurus":
+		weightKey = "sidebar_position"
+	default:
+		return
+	}
+	fmt.Fprintf(buffer, "---\n")
+	fmt.Fprintf(buffer, "title: %s\n", strconv.Quote(entry.name))
+	fmt.Fprintf(buffer, "%s: %d\n", weightKey, weight)
+	if entry.synopsis != "" {
+		fmt.Fprintf(buffer, "description: %s\n", strconv.Quote(entry.synopsis))
+	}
+	fmt.Fprintf(buffer, "---\n\n")
+}
+
+// renderMkDocsNavigationTo renders the nav section of mkdocs.yml, with the
+// page of each package relative to the site directory (the docs_dir).
+func renderMkDocsNavigationTo(buffer *bytes.Buffer, packageList []*_sitePackage) {
+	fmt.Fprintf(buffer, "nav:\n")
+	for _, entry := range packageList {
+		title := entry.path
+		if entry.path == "." {
+			title = entry.name
+		}
+		fmt.Fprintf(buffer, "  - %s: %s\n", strconv.Quote(title), strconv.Quote(entry.page()))
+	}
+}
+
+// siteFormatSupports reports whether the site generator can build pages of
+// the given output format (Hugo has AsciiDoc and reStructuredText support).
+func siteFormatSupports(siteFormat, format string) bool {
+	switch siteFormat {
+	case "docusaurus", "mkdocs":
+		return format == "markdown" || format == "plain"
+	}
+	return true
+}
+
+func validSiteFormat(format string) bool {
+	for _, valid := range siteFormatList {
+		if format == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/doc"
+	"os"
+	"path/filepath"
+	"strings"
+	Template "text/template"
+)
+
+// The index of a split package, named so that GitHub will show it when browsing the directory
+func splitIndexFilename() string {
+	return "README" + renderer().Extension()
+}
+
+// splitTypeFilename is the filename of the page of a type, which (for a type
+// named README) must not be the index (on a case-insensitive file system, either)
+func splitTypeFilename(entry *doc.Type) string {
+	if strings.EqualFold(entry.Name+renderer().Extension(), splitIndexFilename()) {
+		return entry.Name + ".type" + renderer().Extension()
+	}
+	return entry.Name + renderer().Extension()
+}
+
+// EmitTypeTo renders the page of a type (see -split)
+func (self *_document) EmitTypeTo(buffer *bytes.Buffer, entry *doc.Type) {
+	renderTypePageTo(buffer, entry, self)
+}
+
+// emitSplit writes the documentation into directory as an index
+// (with the template, if any) and one file per type, recording
+// each file in the cache entry (if any). The pages it wrote before that
+// it no longer writes (of a type since removed, say) are removed, as the
+// cache entry has them; without one, they stay.
+func emitSplit(document *_document, template *Template.Template, directory string, cacheEntry *_cacheEntry) error {
+	document.split, document.outputDirectory = true, directory
+	defer func() {
+		document.split, document.outputDirectory = false, ""
+	}()
+
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return err
+	}
+
+	index, err := emitDocument(document, template)
+	if err != nil {
+		return fmt.Errorf("Error running template: %v", err)
+	}
+	err = cacheEntry.write(filepath.Join(directory, splitIndexFilename()), []byte(index+"\n"), false)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range document.pkg.Types {
+		page := emitString(func(buffer *bytes.Buffer) {
+			document.EmitTypeTo(buffer, entry)
+			document.EmitSignatureTo(buffer)
+		})
+		err = cacheEntry.write(filepath.Join(directory, splitTypeFilename(entry)), []byte(page+"\n"), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return cacheEntry.removeStale()
+}